@@ -0,0 +1,196 @@
+package grafana
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const annotationsPath = "/api/annotations"
+
+// Types of AnnotationQuery.Type.
+const (
+	AnnotationTypeAnnotation = "annotation"
+	AnnotationTypeAlert      = "alert"
+)
+
+type annotationDTO struct {
+	ID           int64    `json:"id,omitempty"`
+	AlertID      int64    `json:"alertId,omitempty"`
+	DashboardUID string   `json:"dashboardUID,omitempty"`
+	PanelID      int      `json:"panelId,omitempty"`
+	Time         int64    `json:"time,omitempty"`
+	TimeEnd      int64    `json:"timeEnd,omitempty"`
+	Text         string   `json:"text"`
+	Tags         []string `json:"tags"`
+	NewState     string   `json:"newState,omitempty"`
+}
+
+type annotationCreatedDTO struct {
+	ID int64 `json:"id"`
+}
+
+func newAnnotationDTO(a Annotation) annotationDTO {
+	dto := annotationDTO{
+		DashboardUID: a.DashboardUID,
+		PanelID:      a.PanelID,
+		Text:         a.Text,
+		Tags:         a.Tags,
+	}
+
+	if dto.Tags == nil {
+		dto.Tags = []string{}
+	}
+
+	if !a.Time.IsZero() {
+		dto.Time = a.Time.UnixMilli()
+	}
+
+	if !a.TimeEnd.IsZero() {
+		dto.TimeEnd = a.TimeEnd.UnixMilli()
+	}
+
+	return dto
+}
+
+func (d annotationDTO) ToAnnotation() Annotation {
+	a := Annotation{
+		ID:           d.ID,
+		DashboardUID: d.DashboardUID,
+		PanelID:      d.PanelID,
+		Text:         d.Text,
+		Tags:         d.Tags,
+		AlertID:      d.AlertID,
+		State:        d.NewState,
+	}
+
+	if d.Time != 0 {
+		a.Time = time.UnixMilli(d.Time)
+	}
+
+	// Grafana sets timeEnd to time for point annotations
+	if d.TimeEnd != 0 && d.TimeEnd != d.Time {
+		a.TimeEnd = time.UnixMilli(d.TimeEnd)
+	}
+
+	return a
+}
+
+// CreateAnnotation adds the annotation and returns its id. Without a dashboard it is organization-wide,
+// without a panel it is shown on every panel of the dashboard. A zero time is now.
+func (g *grafana) CreateAnnotation(ctx context.Context, a Annotation) (int64, error) {
+	if a.Time.IsZero() {
+		a.Time = time.Now()
+	}
+
+	id, err := g.client.createAnnotation(ctx, newAnnotationDTO(a))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create annotation: %w", err)
+	}
+
+	return id, nil
+}
+
+// UpdateAnnotation replaces the time, text and tags of the annotation with its ID.
+func (g *grafana) UpdateAnnotation(ctx context.Context, a Annotation) error {
+	if err := g.client.updateAnnotation(ctx, a.ID, newAnnotationDTO(a)); err != nil {
+		return fmt.Errorf("failed to update annotation %d: %w", a.ID, err)
+	}
+
+	return nil
+}
+
+// DeleteAnnotation deletes the annotation with the id.
+func (g *grafana) DeleteAnnotation(ctx context.Context, id int64) error {
+	if err := g.client.deleteAnnotation(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete annotation %d: %w", id, err)
+	}
+
+	return nil
+}
+
+// Annotations returns the annotations matching the query, newest first.
+func (g *grafana) Annotations(ctx context.Context, q AnnotationQuery) ([]Annotation, error) {
+	items, err := g.client.annotations(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get annotations: %w", err)
+	}
+
+	annotations := make([]Annotation, 0, len(items))
+	for _, item := range items {
+		annotations = append(annotations, item.ToAnnotation())
+	}
+
+	return annotations, nil
+}
+
+func (q AnnotationQuery) values() url.Values {
+	v := url.Values{}
+
+	if q.DashboardUID != "" {
+		v.Set("dashboardUID", q.DashboardUID)
+	}
+
+	if q.PanelID != 0 {
+		v.Set("panelId", strconv.Itoa(q.PanelID))
+	}
+
+	if !q.From.IsZero() {
+		v.Set("from", strconv.FormatInt(q.From.UnixMilli(), 10))
+	}
+
+	if !q.To.IsZero() {
+		v.Set("to", strconv.FormatInt(q.To.UnixMilli(), 10))
+	}
+
+	for _, tag := range q.Tags {
+		v.Add("tags", tag)
+	}
+
+	if q.MatchAny {
+		v.Set("matchAny", "true")
+	}
+
+	if q.Type != "" {
+		v.Set("type", q.Type)
+	}
+
+	if q.Limit > 0 {
+		v.Set("limit", strconv.Itoa(q.Limit))
+	}
+
+	return v
+}
+
+func (c *client) createAnnotation(ctx context.Context, annotation annotationDTO) (int64, error) {
+	var created annotationCreatedDTO
+
+	if err := c.sendJSON(ctx, operationCreateAnnotation, http.MethodPost, annotationsPath, annotation, &created); err != nil {
+		return 0, err
+	}
+
+	return created.ID, nil
+}
+
+func (c *client) updateAnnotation(ctx context.Context, id int64, annotation annotationDTO) error {
+	return c.sendJSON(ctx, operationUpdateAnnotation, http.MethodPut, fmt.Sprintf("%s/%d", annotationsPath, id), annotation, nil)
+}
+
+func (c *client) deleteAnnotation(ctx context.Context, id int64) error {
+	return c.sendJSON(ctx, operationDeleteAnnotation, http.MethodDelete, fmt.Sprintf("%s/%d", annotationsPath, id), nil, nil)
+}
+
+func (c *client) annotations(ctx context.Context, q AnnotationQuery) ([]annotationDTO, error) {
+	var annotations []annotationDTO
+
+	path := fmt.Sprintf("%s?%s", annotationsPath, q.values().Encode())
+
+	if err := c.sendJSON(ctx, operationAnnotations, http.MethodGet, path, nil, &annotations); err != nil {
+		return nil, err
+	}
+
+	return annotations, nil
+}
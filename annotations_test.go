@@ -0,0 +1,158 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCreateAnnotation(t *testing.T) {
+	f, inst := newFakeGrafanaClient(t)
+
+	var got annotationDTO
+
+	f.handle(annotationsPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get(contentTypeHeader) != jsonContentType {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		got = annotationDTO{}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("malformed annotation: %s", err)
+		}
+
+		w.Write([]byte(`{"message":"Annotation added","id":42}`))
+	})
+
+	start := time.Date(2022, 4, 15, 10, 0, 0, 0, time.UTC)
+
+	id, err := inst.CreateAnnotation(context.Background(), Annotation{
+		DashboardUID: fakeDashboardUID,
+		PanelID:      4,
+		Time:         start,
+		TimeEnd:      start.Add(10 * time.Minute),
+		Text:         "Validator upgrade v1.2.0",
+		Tags:         []string{"deploy", "mainnet"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := annotationDTO{
+		DashboardUID: fakeDashboardUID,
+		PanelID:      4,
+		Time:         1650016800000,
+		TimeEnd:      1650017400000,
+		Text:         "Validator upgrade v1.2.0",
+		Tags:         []string{"deploy", "mainnet"},
+	}
+
+	if id != 42 || !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong annotation %d: %+v", id, got)
+	}
+
+	// organization-wide annotations of now
+	if _, err = inst.CreateAnnotation(context.Background(), Annotation{Text: "Incident"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.DashboardUID != "" || got.PanelID != 0 || got.Time == 0 || got.TimeEnd != 0 || got.Tags == nil {
+		t.Fatalf("wrong organization annotation: %+v", got)
+	}
+}
+
+func TestUpdateAndDeleteAnnotation(t *testing.T) {
+	f, inst := newFakeGrafanaClient(t)
+
+	var methods []string
+
+	f.handle(annotationsPath+"/42", func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		w.Write([]byte(`{"message":"ok"}`))
+	})
+
+	if err := inst.UpdateAnnotation(context.Background(), Annotation{ID: 42, Text: "Rolled back"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := inst.DeleteAnnotation(context.Background(), 42); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(methods, []string{http.MethodPut, http.MethodDelete}) {
+		t.Fatalf("wrong requests: %v", methods)
+	}
+
+	if err := inst.DeleteAnnotation(context.Background(), 7); err == nil {
+		t.Fatal("expected error for missing annotation")
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	f, inst := newFakeGrafanaClient(t)
+
+	var query string
+
+	f.handle(annotationsPath, func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.Write([]byte(`[
+			{"id":3,"dashboardUID":"monitors","panelId":4,"time":1650016800000,"timeEnd":1650017400000,"text":"Validator upgrade","tags":["deploy"]},
+			{"id":2,"dashboardUID":"monitors","panelId":0,"time":1650013200000,"timeEnd":1650013200000,"text":"Restart","tags":[]},
+			{"id":1,"alertId":5,"dashboardUID":"monitors","panelId":4,"time":1650009600000,"newState":"alerting","text":"","tags":[]}]`))
+	})
+
+	annotations, err := inst.Annotations(context.Background(), AnnotationQuery{
+		DashboardUID: fakeDashboardUID,
+		From:         time.UnixMilli(1650009600000),
+		To:           time.UnixMilli(1650020400000),
+		Tags:         []string{"deploy", "mainnet"},
+		MatchAny:     true,
+		Limit:        10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if query != "dashboardUID=monitors&from=1650009600000&limit=10&matchAny=true&tags=deploy&tags=mainnet&to=1650020400000" {
+		t.Fatalf("wrong query %s", query)
+	}
+
+	if len(annotations) != 3 {
+		t.Fatalf("got %d annotations, want 3", len(annotations))
+	}
+
+	if a := annotations[0]; a.ID != 3 || a.TimeEnd.Sub(a.Time) != 10*time.Minute || a.Tags[0] != "deploy" {
+		t.Fatalf("wrong region annotation: %+v", a)
+	}
+
+	if !annotations[1].TimeEnd.IsZero() {
+		t.Fatalf("point annotation has an end: %+v", annotations[1])
+	}
+
+	if a := annotations[2]; a.AlertID != 5 || a.State != "alerting" {
+		t.Fatalf("wrong alert annotation: %+v", a)
+	}
+
+	jailed := Panel{ID: 4, DashboardUID: fakeDashboardUID}
+	updates := Panel{ID: 2, DashboardUID: fakeDashboardUID}
+
+	if !annotations[0].Applies(jailed) || annotations[0].Applies(updates) {
+		t.Fatal("panel annotation applies to the wrong panels")
+	}
+
+	if !annotations[1].Applies(updates) || annotations[1].Applies(Panel{ID: 2, DashboardUID: "other"}) {
+		t.Fatal("dashboard annotation applies to the wrong panels")
+	}
+
+	if !(Annotation{Text: "Incident"}).Applies(Panel{ID: 2, DashboardUID: "other"}) {
+		t.Fatal("organization annotation must apply to every panel")
+	}
+}
@@ -1,35 +1,68 @@
 package grafana
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const (
 	dashboardPath   = "/api/dashboards/uid/"
 	alertsPath      = "/api/alerts"
-	datasourcesPath = "/api/datasources/proxy/1/api/v1/query_range"
+	datasourcesPath = "/api/datasources/proxy/1"
+
+	instantQueryPath = "/api/v1/query"
+	rangeQueryPath   = "/api/v1/query_range"
 )
 
 const (
 	authHeader = "Authorization"
 
-	// in our case it doesn't matter, but required
-	defaultStepQueryParam = 10
-	multipleLegendFormat  = "{{label}}"
+	defaultRangeStep     = 10 * time.Second
+	multipleLegendFormat = "{{label}}"
 )
 
+var errProxyForbidden = errors.New("datasource proxy is forbidden")
+
 type client struct {
 	url    string
 	token  string
 	client http.Client
+
+	telemetry *telemetry
+	logger    Logger
+	debug     bool
+
+	// orgID is the organization of requests without one in their context, zero for the default one of the token
+	orgID int64
+
+	queryAPI       QueryAPI
+	proxyForbidden int32
+	prometheusURLs map[string]string
+
+	datasourcesMu  sync.Mutex
+	datasources    map[int64][]datasourceInfoDTO
+	datasourcesErr map[int64]datasourcesFailure
+
+	libraryPanelsMu   sync.Mutex
+	libraryPanels     map[string]libraryPanelEntry
+	libraryPanelCalls map[string]*libraryPanelCall
+}
+
+// prometheusAPI is a Prometheus HTTP API reached either through the Grafana datasource proxy or directly.
+type prometheusAPI struct {
+	url     string
+	proxied bool
 }
 
 func newClient(url string, token string, timeout time.Duration) *client {
@@ -38,14 +71,40 @@ func newClient(url string, token string, timeout time.Duration) *client {
 	}
 
 	return &client{
-		client: http.Client{Timeout: timeout},
-		url:    url,
-		token:  token,
+		client:    http.Client{Timeout: timeout},
+		url:       url,
+		token:     token,
+		telemetry: newTelemetry(telemetryConfig{}),
+		logger:    nopLogger{},
 	}
 }
 
 func (c *client) getDashboard(ctx context.Context, dashboardUID string) (dashboardData, error) {
-	var dashboard dashboardDTO
+	dashboard, _, err := c.getDashboardResponse(ctx, dashboardUID)
+	if err != nil {
+		return dashboardData{}, err
+	}
+
+	data := dashboard.Data()
+	if err = c.resolveLibraryPanels(ctx, data.Panels); err != nil {
+		return dashboardData{}, err
+	}
+
+	return data, nil
+}
+
+// getDashboardResponse returns the dashboard along with its raw JSON model. Library panels are left
+// unresolved, see resolveLibraryPanels.
+func (c *client) getDashboardResponse(ctx context.Context, dashboardUID string) (_ *dashboardDTO, _ json.RawMessage, err error) {
+	ctx, op := c.telemetry.start(ctx, operationGetDashboard, dashboardUIDKey.String(dashboardUID))
+	defer func() { op.end(err) }()
+
+	var (
+		dashboard dashboardDTO
+		raw       struct {
+			Dashboard json.RawMessage `json:"dashboard"`
+		}
+	)
 
 	req, err := http.NewRequestWithContext(
 		ctx,
@@ -53,35 +112,42 @@ func (c *client) getDashboard(ctx context.Context, dashboardUID string) (dashboa
 		fmt.Sprintf("%s%s%s", c.url, dashboardPath, dashboardUID),
 		nil)
 	if err != nil {
-		return dashboardData{}, fmt.Errorf("failed to create NewRequestWithContext: %w", err)
+		return nil, nil, fmt.Errorf("failed to create NewRequestWithContext: %w", err)
 	}
 
 	req.Header.Add(authHeader, c.token)
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
-		return dashboardData{}, fmt.Errorf("failed to do request: %w", err)
+		return nil, nil, fmt.Errorf("failed to do request: %w", err)
 	}
 
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return dashboardData{}, fmt.Errorf("failed: status code is %d", resp.StatusCode)
+		return nil, nil, fmt.Errorf("failed: status code is %d", resp.StatusCode)
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return dashboardData{}, fmt.Errorf("failed to read dashboard response body: %w", err)
+		return nil, nil, fmt.Errorf("failed to read dashboard response body: %w", err)
 	}
 
 	if err = json.Unmarshal(body, &dashboard); err != nil {
-		return dashboardData{}, fmt.Errorf("failed to unmarshal dashboard response: %w", err)
+		return nil, nil, fmt.Errorf("failed to unmarshal dashboard response: %w", err)
 	}
 
-	return dashboard.Data(), nil
+	if err = json.Unmarshal(body, &raw); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal dashboard response: %w", err)
+	}
+
+	return &dashboard, raw.Dashboard, nil
 }
 
-func (c *client) alertStates(ctx context.Context, dashboardID int) (map[int]Alert, error) {
+func (c *client) alertStates(ctx context.Context, dashboardID int) (_ map[int]Alert, err error) {
+	ctx, op := c.telemetry.start(ctx, operationAlertStates, dashboardIDKey.Int(dashboardID))
+	defer func() { op.end(err) }()
+
 	var alertStates alertStatesDTO
 
 	req, err := http.NewRequestWithContext(
@@ -100,7 +166,7 @@ func (c *client) alertStates(ctx context.Context, dashboardID int) (map[int]Aler
 
 	req.Header.Add(authHeader, c.token)
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to do request: %w", err)
 	}
@@ -123,74 +189,390 @@ func (c *client) alertStates(ctx context.Context, dashboardID int) (map[int]Aler
 	return alertStates.ToAlertMap(), nil
 }
 
-func (c *client) currentValues(ctx context.Context, queries []expr) ([]CurrentValue, error) {
+// currentValues evaluates the queries at the given time. Queries of datasources mapped to Prometheus
+// go straight to it, the rest go through Grafana.
+func (c *client) currentValues(ctx context.Context, queries []expr, at time.Time) ([]CurrentValue, error) {
+	if len(c.prometheusURLs) == 0 {
+		return c.grafanaCurrentValues(ctx, queries, at)
+	}
+
+	result := make([]CurrentValue, len(queries))
+	viaGrafana := make([]expr, 0, len(queries))
+	viaGrafanaIndexes := make([]int, 0, len(queries))
+
+	for i, query := range queries {
+		api, ok := c.directAPI(ctx, query.Datasource)
+		if !ok {
+			viaGrafana = append(viaGrafana, query)
+			viaGrafanaIndexes = append(viaGrafanaIndexes, i)
+
+			continue
+		}
+
+		currentLabelValues, err := c.promQuery(ctx, api, query.Query, at)
+		if err != nil {
+			return nil, fmt.Errorf("error getting current values by query: %s; error: %s", query.Query, err)
+		}
+
+		result[i] = CurrentValue{
+			Query:  query.Query,
+			Values: applyLegendFormat(query, currentLabelValues),
+		}
+	}
+
+	if len(viaGrafana) == 0 {
+		return result, nil
+	}
+
+	grafanaValues, err := c.grafanaCurrentValues(ctx, viaGrafana, at)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, value := range grafanaValues {
+		result[viaGrafanaIndexes[i]] = value
+	}
+
+	return result, nil
+}
+
+// reducedValues queries every target over the range and turns the series into the values a stat panel
+// displays: a calculation per series or all values, of the shown fields only.
+func (c *client) reducedValues(ctx context.Context, queries []expr, from, to time.Time, reduce reduceOptions) ([]CurrentValue, error) {
 	result := make([]CurrentValue, 0, len(queries))
 
 	for _, query := range queries {
-		currentLabelValues, err := c.datasource(ctx, query.Query)
+		series, err := c.exprQueryRange(ctx, query, from, to)
 		if err != nil {
-			return nil, fmt.Errorf("error getting current values by query: %s; error: %s", query, err)
+			return nil, fmt.Errorf("error getting range values by query: %s; error: %w", query.Query, err)
+		}
+
+		result = append(result, CurrentValue{
+			Query:  query.Query,
+			Values: applyLegendFormat(query, reduce.seriesValues(series)),
+		})
+	}
+
+	return reduce.display(result), nil
+}
+
+// exprQueryRange runs the range query of a panel target against its datasource with the step Grafana would use.
+func (c *client) exprQueryRange(ctx context.Context, query expr, from, to time.Time) ([]Series, error) {
+	step := queryStep(query, from, to)
+
+	api, ok := c.directAPI(ctx, query.Datasource)
+	if ok {
+		return c.promQueryRange(ctx, api, query.Query, from, to, step)
+	}
+
+	if c.useUnifiedAPI() {
+		return c.unifiedExprQueryRange(ctx, query, from, to, step)
+	}
+
+	series, err := c.promQueryRange(ctx, c.proxyAPI(), query.Query, from, to, step)
+	if errors.Is(err, errProxyForbidden) && c.fallbackToUnifiedAPI(ctx) {
+		return c.unifiedExprQueryRange(ctx, query, from, to, step)
+	}
+
+	return series, err
+}
+
+// queryStep spreads maxDataPoints over the range, but not closer than the query interval or a second.
+func queryStep(query expr, from, to time.Time) time.Duration {
+	maxDataPoints := query.MaxDataPoints
+	if maxDataPoints <= 0 {
+		maxDataPoints = defaultMaxDataPoints
+	}
+
+	step := to.Sub(from) / time.Duration(maxDataPoints)
+
+	if interval := time.Duration(query.IntervalMs) * time.Millisecond; step < interval {
+		step = interval
+	}
+
+	if rounded := step.Truncate(time.Second); rounded < step {
+		step = rounded + time.Second
+	}
+
+	if step < time.Second {
+		step = time.Second
+	}
+
+	return step
+}
+
+// query evaluates an instant query against the default datasource. A zero at means now.
+func (c *client) query(ctx context.Context, query string, at time.Time) ([]LabelValue, error) {
+	api, ok := c.directAPI(ctx, datasourceRef{})
+	if ok {
+		return c.promQuery(ctx, api, query, at)
+	}
+
+	return c.grafanaQuery(ctx, query, at)
+}
+
+func (c *client) queryRange(ctx context.Context, query string, from, to time.Time, step time.Duration) ([]Series, error) {
+	if step <= 0 {
+		step = defaultRangeStep
+	}
+
+	api, ok := c.directAPI(ctx, datasourceRef{})
+	if ok {
+		return c.promQueryRange(ctx, api, query, from, to, step)
+	}
+
+	return c.grafanaQueryRange(ctx, query, from, to, step)
+}
+
+func (c *client) grafanaCurrentValues(ctx context.Context, queries []expr, at time.Time) ([]CurrentValue, error) {
+	if c.useUnifiedAPI() {
+		return c.unifiedCurrentValues(ctx, queries, at)
+	}
+
+	result := make([]CurrentValue, 0, len(queries))
+
+	for _, query := range queries {
+		currentLabelValues, err := c.promQuery(ctx, c.proxyAPI(), query.Query, at)
+		if errors.Is(err, errProxyForbidden) && c.fallbackToUnifiedAPI(ctx) {
+			return c.unifiedCurrentValues(ctx, queries, at)
 		}
 
-		if query.LegendFormat != multipleLegendFormat {
-			for i := range currentLabelValues {
-				currentLabelValues[i].Label = query.LegendFormat
-			}
+		if err != nil {
+			return nil, fmt.Errorf("error getting current values by query: %s; error: %s", query.Query, err)
 		}
 
 		result = append(result, CurrentValue{
 			Query:  query.Query,
-			Values: currentLabelValues,
+			Values: applyLegendFormat(query, currentLabelValues),
 		})
 	}
 
 	return result, nil
 }
 
-func (c *client) datasource(ctx context.Context, query string) ([]LabelValue, error) {
+func (c *client) grafanaQuery(ctx context.Context, query string, at time.Time) ([]LabelValue, error) {
+	if c.useUnifiedAPI() {
+		return c.unifiedQuery(ctx, query, at)
+	}
+
+	values, err := c.promQuery(ctx, c.proxyAPI(), query, at)
+	if errors.Is(err, errProxyForbidden) && c.fallbackToUnifiedAPI(ctx) {
+		return c.unifiedQuery(ctx, query, at)
+	}
+
+	return values, err
+}
+
+func (c *client) grafanaQueryRange(ctx context.Context, query string, from, to time.Time, step time.Duration) ([]Series, error) {
+	if c.useUnifiedAPI() {
+		return c.unifiedQueryRange(ctx, query, from, to, step)
+	}
+
+	series, err := c.promQueryRange(ctx, c.proxyAPI(), query, from, to, step)
+	if errors.Is(err, errProxyForbidden) && c.fallbackToUnifiedAPI(ctx) {
+		return c.unifiedQueryRange(ctx, query, from, to, step)
+	}
+
+	return series, err
+}
+
+func (c *client) promQuery(ctx context.Context, api prometheusAPI, query string, at time.Time) ([]LabelValue, error) {
+	q := url.Values{}
+	q.Add("query", query)
+
+	if !at.IsZero() {
+		q.Add("time", formatQueryTime(at))
+	}
+
+	datasource, err := c.datasource(ctx, api, instantQueryPath, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return datasource.ToLabelValues()
+}
+
+func (c *client) promQueryRange(ctx context.Context, api prometheusAPI, query string, from, to time.Time, step time.Duration) ([]Series, error) {
+	q := url.Values{}
+	q.Add("query", query)
+	q.Add("start", formatQueryTime(from))
+	q.Add("end", formatQueryTime(to))
+	q.Add("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+
+	datasource, err := c.datasource(ctx, api, rangeQueryPath, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return datasource.ToSeries()
+}
+
+func (c *client) proxyAPI() prometheusAPI {
+	return prometheusAPI{
+		url:     c.url + datasourcesPath,
+		proxied: true,
+	}
+}
+
+// useUnifiedAPI reports whether queries should go through /api/ds/query instead of the datasource proxy.
+func (c *client) useUnifiedAPI() bool {
+	switch c.queryAPI {
+	case QueryAPIUnified:
+		return true
+	case QueryAPIProxy:
+		return false
+	}
+
+	return atomic.LoadInt32(&c.proxyForbidden) == 1
+}
+
+// fallbackToUnifiedAPI remembers that the datasource proxy is forbidden if the client is allowed to fall back.
+func (c *client) fallbackToUnifiedAPI(ctx context.Context) bool {
+	if c.queryAPI != QueryAPIAuto {
+		return false
+	}
+
+	atomic.StoreInt32(&c.proxyForbidden, 1)
+
+	c.logger.Log(ctx, LogLevelWarn, "datasource proxy is forbidden, retrying with "+dsQueryPath)
+
+	return true
+}
+
+func (c *client) datasource(ctx context.Context, api prometheusAPI, path string, q url.Values) (_ datasourceDTO, err error) {
+	ctx, op := c.telemetry.start(ctx, operationDatasource, queryKey.String(q.Get("query")))
+	defer func() { op.end(err) }()
+
 	var datasource datasourceDTO
 
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodGet,
-		fmt.Sprintf("%s%s", c.url, datasourcesPath),
+		fmt.Sprintf("%s%s", api.url, path),
 		nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create NewRequestWithContext: %w", err)
+		return datasourceDTO{}, fmt.Errorf("failed to create NewRequestWithContext: %w", err)
 	}
 
-	now := time.Now().Unix()
+	req.URL.RawQuery = q.Encode()
 
-	q := req.URL.Query()
+	if api.proxied {
+		req.Header.Add(authHeader, c.token)
+	}
 
-	q.Add("query", query)
-	q.Add("start", strconv.FormatInt(now, 10))
-	q.Add("end", strconv.FormatInt(now, 10))
-	q.Add("step", strconv.FormatInt(defaultStepQueryParam, 10))
+	resp, err := c.do(req)
+	if err != nil {
+		return datasourceDTO{}, fmt.Errorf("failed to do request: %w", err)
+	}
 
-	req.URL.RawQuery = q.Encode()
+	defer resp.Body.Close()
+
+	if api.proxied && resp.StatusCode == http.StatusForbidden {
+		return datasourceDTO{}, fmt.Errorf("%w: status code is %d", errProxyForbidden, resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return datasourceDTO{}, fmt.Errorf("failed to read datasource response body: %w", err)
+	}
+
+	// Prometheus reports bad queries with a 4xx status and an error in the body,
+	// so the body is decoded before the status code is checked.
+	if err = json.Unmarshal(body, &datasource); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return datasourceDTO{}, fmt.Errorf("failed: status code is %d", resp.StatusCode)
+		}
+
+		return datasourceDTO{}, fmt.Errorf("failed to unmarshal datasource response: %w", err)
+	}
+
+	if datasource.Error != "" {
+		return datasourceDTO{}, errors.New(datasource.Error)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return datasourceDTO{}, fmt.Errorf("failed: status code is %d", resp.StatusCode)
+	}
+
+	return datasource, nil
+}
+
+func applyLegendFormat(query expr, values []LabelValue) []LabelValue {
+	if query.LegendFormat != multipleLegendFormat {
+		for i := range values {
+			values[i].Label = query.LegendFormat
+		}
+	}
+
+	return values
+}
+
+func formatQueryTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixMilli())/1000, 'f', 3, 64)
+}
+
+// sendJSON sends in as JSON, unless it is nil, and unmarshals the response into out, unless it is nil.
+// A 207 Multi-Status is a success whose body tells what failed; the error of other statuses carries
+// the message of Grafana.
+func (c *client) sendJSON(ctx context.Context, operation, method, path string, in, out interface{}) (err error) {
+	ctx, op := c.telemetry.start(ctx, operation)
+	defer func() { op.end(err) }()
+
+	var payload []byte
+
+	if in != nil {
+		if payload, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s%s", c.url, path), bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create NewRequestWithContext: %w", err)
+	}
 
 	req.Header.Add(authHeader, c.token)
 
-	resp, err := c.client.Do(req)
+	if in != nil {
+		req.Header.Add(contentTypeHeader, jsonContentType)
+	}
+
+	resp, err := c.do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to do request: %w", err)
+		return fmt.Errorf("failed to do request: %w", err)
 	}
 
 	defer resp.Body.Close()
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read dashboard response body: %s: %w", body, err)
+		return fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	if err = json.Unmarshal(body, &datasource); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal dashboard response: %w", err)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusMultiStatus {
+		return fmt.Errorf("failed: status code is %d: %s", resp.StatusCode, responseMessage(body))
 	}
 
-	if datasource.Error != "" {
-		return nil, errors.New(datasource.Error)
+	if out == nil {
+		return nil
+	}
+
+	if err = json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return nil
+}
+
+// responseMessage is the message of a Grafana error response, or the body itself.
+func responseMessage(body []byte) string {
+	var response struct {
+		Message string `json:"message"`
+	}
+
+	if json.Unmarshal(body, &response) == nil && response.Message != "" {
+		return response.Message
 	}
 
-	return datasource.ToLabelValues(), nil
+	return strings.TrimSpace(string(body))
 }
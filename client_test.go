@@ -2,14 +2,11 @@ package grafana
 
 import (
 	"context"
-	"os"
 	"testing"
+	"time"
 )
 
 func TestCurrentValues(t *testing.T) {
-	if testing.Short() {
-		t.Skip("skipping test in short mode")
-	}
 	var (
 		queriesTestDataMap = map[string]string{
 			"update_global_index_gas_used{}":   "gas used",
@@ -38,24 +35,16 @@ func TestCurrentValues(t *testing.T) {
 		}
 	)
 
-	token = os.Getenv("GRAFANA_TOKEN")
-	if token == "" {
-		t.Fatal("Failed grafana test: no token")
-	}
+	f := newFakeGrafana(t)
 
-	addr = os.Getenv("GRAFANA_ADDR")
-	if addr == "" {
-		t.Fatal("Failed grafana test: no addr")
-	}
+	client := newClient(f.URL, fakeToken, timeout)
 
-	client := newClient(httpPrefix+addr, token, timeout)
-
-	datasources, err := client.currentValues(context.Background(), queriesTestData)
+	datasources, err := client.currentValues(context.Background(), queriesTestData, time.Time{})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if len(datasources) == 0 {
+	if len(datasources) != len(queriesTestData) {
 		t.Fatal("current values is empty!")
 	}
 
@@ -73,9 +62,20 @@ func TestCurrentValues(t *testing.T) {
 	}
 }
 
+func TestCurrentValuesPrometheusError(t *testing.T) {
+	f := newFakeGrafana(t)
+
+	client := newClient(f.URL, fakeToken, timeout)
+
+	_, err := client.currentValues(context.Background(), []expr{{Query: "unknown_metric"}}, time.Time{})
+	if err == nil {
+		t.Fatal("expected error for unknown query")
+	}
+}
+
 func checkSingleValues(t *testing.T, values []LabelValue, label string) {
-	if len(values) > 1 {
-		t.Fatal("lenght of values more than 1, but should be 1!")
+	if len(values) != 1 {
+		t.Fatal("lenght of values should be 1!")
 	}
 
 	if values[0].Label != label {
@@ -88,7 +88,7 @@ func checkSingleValues(t *testing.T, values []LabelValue, label string) {
 }
 
 func checkMultipleValues(t *testing.T, values []LabelValue, wrongLabel string) {
-	if len(values) == 1 {
+	if len(values) < 2 {
 		t.Fatal("lenght of values should be more than 1")
 	}
 
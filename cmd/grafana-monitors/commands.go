@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	grafana "github.com/lidofinance/grafana-monitors-client"
+)
+
+const noValue = "-"
+
+func panelsCommand(ctx context.Context, inst grafana.Grafana, cfg config, args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	panels, err := inst.Panels(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	sortPanels(panels)
+
+	if cfg.json {
+		return writeJSON(stdout, panels)
+	}
+
+	return writePanelsTable(stdout, panels)
+}
+
+func panelCommand(ctx context.Context, inst grafana.Grafana, cfg config, args []string, stdout io.Writer) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+
+	panel, err := inst.GetGrafanaPanel(args[1], args[0])
+	if err != nil {
+		return err
+	}
+
+	if cfg.json {
+		return writeJSON(stdout, panel)
+	}
+
+	return writePanelsTable(stdout, []grafana.Panel{*panel})
+}
+
+func alertsCommand(ctx context.Context, inst grafana.Grafana, cfg config, args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	panels, err := inst.Panels(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	sortPanels(panels)
+
+	alerts := make([]grafana.Panel, 0, len(panels))
+
+	for _, p := range panels {
+		if p.Alert.Name != "" {
+			alerts = append(alerts, p)
+		}
+	}
+
+	if cfg.json {
+		return writeJSON(stdout, alerts)
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(w, "PANEL\tALERT\tSTATE")
+
+	for _, p := range alerts {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", p.Title, p.Alert.Name, orNoValue(p.Alert.State))
+	}
+
+	return w.Flush()
+}
+
+func orgsCommand(ctx context.Context, inst grafana.Grafana, cfg config, args []string, stdout io.Writer) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	orgs, err := inst.Orgs(ctx)
+	if err != nil {
+		return err
+	}
+
+	if cfg.json {
+		return writeJSON(stdout, orgs)
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(w, "ID\tNAME\tROLE")
+
+	for _, o := range orgs {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", o.ID, o.Name, o.Role)
+	}
+
+	return w.Flush()
+}
+
+func queryCommand(ctx context.Context, inst grafana.Grafana, cfg config, args []string, stdout, stderr io.Writer) error {
+	var at string
+
+	flags := flag.NewFlagSet("query", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&at, "time", "", "evaluation time in RFC3339, now by default")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil || len(positional) != 1 {
+		return errUsage
+	}
+
+	var evaluationTime time.Time
+
+	if at != "" {
+		t, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			return fmt.Errorf("invalid -time: %w", err)
+		}
+
+		evaluationTime = t
+	}
+
+	values, err := inst.Query(ctx, positional[0], evaluationTime)
+	if err != nil {
+		return err
+	}
+
+	if cfg.json {
+		return writeJSON(stdout, values)
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(w, "LABEL\tVALUE")
+
+	for _, v := range values {
+		fmt.Fprintf(w, "%s\t%s\n", orNoValue(v.Label), displayValue(v))
+	}
+
+	return w.Flush()
+}
+
+func renderCommand(ctx context.Context, inst grafana.Grafana, args []string, stdout, stderr io.Writer) error {
+	var output string
+
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&output, "o", "", "output file, <panel title>.png by default")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil || len(positional) != 2 {
+		return errUsage
+	}
+
+	panel, err := inst.GetGrafanaPanel(positional[1], positional[0])
+	if err != nil {
+		return err
+	}
+
+	image, err := inst.GetPanelPicture(panel.Image)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		output = fileName(panel.Title) + ".png"
+	}
+
+	if err = ioutil.WriteFile(output, image, 0o644); err != nil {
+		return fmt.Errorf("failed to write image: %w", err)
+	}
+
+	fmt.Fprintln(stdout, output)
+
+	return nil
+}
+
+// parseInterspersed parses flags given before, between or after the positional arguments, e.g.
+// render <uid> <title> -o panel.png, and returns the positional ones.
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+func writePanelsTable(out io.Writer, panels []grafana.Panel) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(w, "PANEL\tALERT\tLABEL\tVALUE")
+
+	for _, p := range panels {
+		alert := orNoValue(p.Alert.State)
+
+		var printed bool
+
+		for _, cv := range p.CurrentValues {
+			for _, v := range cv.Values {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Title, alert, orNoValue(v.Label), displayValue(v))
+
+				printed = true
+			}
+		}
+
+		if !printed {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Title, alert, noValue, noValue)
+		}
+	}
+
+	return w.Flush()
+}
+
+func writeJSON(out io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(v)
+}
+
+// sortPanels orders panels by title; Panels returns them in dashboard order.
+func sortPanels(panels []grafana.Panel) {
+	sort.SliceStable(panels, func(i, j int) bool {
+		return panels[i].Title < panels[j].Title
+	})
+}
+
+func fileName(title string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', ' ':
+			return '_'
+		}
+
+		return r
+	}, title)
+}
+
+// displayValue is the value as Grafana displays it when the panel formats it, the raw value otherwise.
+func displayValue(v grafana.LabelValue) string {
+	if v.FormattedValue != "" {
+		return v.FormattedValue
+	}
+
+	return v.Value
+}
+
+func orNoValue(s string) string {
+	if s == "" {
+		return noValue
+	}
+
+	return s
+}
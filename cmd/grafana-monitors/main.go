@@ -0,0 +1,143 @@
+// Command grafana-monitors inspects Grafana dashboards, panels and alerts.
+//
+// Usage:
+//
+//	grafana-monitors [flags] panels <dashboard uid>
+//	grafana-monitors [flags] panel <dashboard uid> <panel title>
+//	grafana-monitors [flags] alerts <dashboard uid>
+//	grafana-monitors [flags] query <expr>
+//	grafana-monitors [flags] render [-o file.png] <dashboard uid> <panel title>
+//	grafana-monitors [flags] orgs
+//
+// The Grafana address and token default to the GRAFANA_ADDR and GRAFANA_TOKEN environment variables.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	grafana "github.com/lidofinance/grafana-monitors-client"
+)
+
+const (
+	addrEnv  = "GRAFANA_ADDR"
+	tokenEnv = "GRAFANA_TOKEN"
+)
+
+var errUsage = errors.New("usage")
+
+type config struct {
+	addr    string
+	token   string
+	timeout time.Duration
+	orgID   int64
+	json    bool
+	attrs   grafana.ImageAttributes
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	var cfg config
+
+	flags := flag.NewFlagSet("grafana-monitors", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { usage(flags) }
+
+	flags.StringVar(&cfg.addr, "addr", "", "Grafana address, $"+addrEnv+" by default")
+	flags.StringVar(&cfg.token, "token", "", "Grafana authorization header value, $"+tokenEnv+" by default")
+	flags.DurationVar(&cfg.timeout, "timeout", 30*time.Second, "request timeout")
+	flags.Int64Var(&cfg.orgID, "org", 0, "organization id, the default organization of the token by default")
+	flags.BoolVar(&cfg.json, "json", false, "print JSON instead of a table")
+	flags.IntVar(&cfg.attrs.Width, "width", 1000, "rendered image width")
+	flags.IntVar(&cfg.attrs.Height, "height", 500, "rendered image height")
+	flags.StringVar(&cfg.attrs.Timezone, "tz", "UTC", "rendered image timezone")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+
+		return 2
+	}
+
+	// env values are applied after parsing so that the token never shows up in the usage defaults
+	if cfg.addr == "" {
+		cfg.addr = os.Getenv(addrEnv)
+	}
+
+	if cfg.token == "" {
+		cfg.token = os.Getenv(tokenEnv)
+	}
+
+	if cfg.addr == "" {
+		fmt.Fprintf(stderr, "grafana address is required: set -addr or $%s\n", addrEnv)
+
+		return 2
+	}
+
+	inst := grafana.NewGrafana(cfg.addr, cfg.token, cfg.timeout, cfg.attrs, grafana.WithOrgID(cfg.orgID))
+	ctx := context.Background()
+
+	var err error
+
+	command, commandArgs := flags.Arg(0), flags.Args()[1:]
+
+	switch command {
+	case "panels":
+		err = panelsCommand(ctx, inst, cfg, commandArgs, stdout)
+	case "panel":
+		err = panelCommand(ctx, inst, cfg, commandArgs, stdout)
+	case "alerts":
+		err = alertsCommand(ctx, inst, cfg, commandArgs, stdout)
+	case "query":
+		err = queryCommand(ctx, inst, cfg, commandArgs, stdout, stderr)
+	case "render":
+		err = renderCommand(ctx, inst, commandArgs, stdout, stderr)
+	case "orgs":
+		err = orgsCommand(ctx, inst, cfg, commandArgs, stdout)
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n", command)
+		flags.Usage()
+
+		return 2
+	}
+
+	if errors.Is(err, errUsage) {
+		flags.Usage()
+
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+
+		return 1
+	}
+
+	return 0
+}
+
+func usage(flags *flag.FlagSet) {
+	out := flags.Output()
+
+	fmt.Fprintln(out, `Usage:
+  grafana-monitors [flags] panels <dashboard uid>
+  grafana-monitors [flags] panel <dashboard uid> <panel title>
+  grafana-monitors [flags] alerts <dashboard uid>
+  grafana-monitors [flags] query [-time RFC3339] <expr>
+  grafana-monitors [flags] render [-o file.png] <dashboard uid> <panel title>
+  grafana-monitors [flags] orgs
+
+Flags:`)
+	flags.PrintDefaults()
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestGrafana(t *testing.T) *httptest.Server {
+	fixture := func(name string) []byte {
+		body, err := ioutil.ReadFile(filepath.Join("..", "..", "testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return body
+	}
+
+	var queries, ranges map[string]json.RawMessage
+	if err := json.Unmarshal(fixture("queries.json"), &queries); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := json.Unmarshal(fixture("ranges.json"), &ranges); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/dashboards/uid/monitors":
+			w.Write(fixture("dashboard.json"))
+		case r.URL.Path == "/api/alerts":
+			w.Write(fixture("alerts.json"))
+		case r.URL.Path == "/api/datasources/proxy/1/api/v1/query":
+			w.Write(queries[r.URL.Query().Get("query")])
+		case r.URL.Path == "/api/datasources/proxy/1/api/v1/query_range":
+			w.Write(ranges[r.URL.Query().Get("query")])
+		case r.URL.Path == "/api/user/orgs":
+			w.Write([]byte(`[{"orgId":1,"name":"Mainnet","role":"Viewer"},{"orgId":2,"name":"Testnet","role":"Admin"}]`))
+		case strings.HasPrefix(r.URL.Path, "/render/"):
+			w.Write(fixture("panel.png"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestCommands(t *testing.T) {
+	server := newTestGrafana(t)
+	output := filepath.Join(t.TempDir(), "panel.png")
+	trailing := filepath.Join(t.TempDir(), "trailing.png")
+
+	tests := map[string]struct {
+		args     []string
+		contains []string
+	}{
+		"panels": {
+			args:     []string{"panels", "monitors"},
+			contains: []string{"Config checksum", "reward", "3735928559", "alerting", "512.3 K"},
+		},
+		"panels json": {
+			args:     []string{"-json", "panels", "monitors"},
+			contains: []string{`"title": "Update global index"`, `"label": "gas used"`},
+		},
+		"panel": {
+			args:     []string{"panel", "monitors", "Slashing: Jailed Validators"},
+			contains: []string{"jailed", "2"},
+		},
+		"alerts": {
+			args:     []string{"alerts", "monitors"},
+			contains: []string{"Jailed validators alert", "alerting"},
+		},
+		"query": {
+			args:     []string{"query", "config_crc32"},
+			contains: []string{"hub", "305419896"},
+		},
+		"orgs": {
+			args:     []string{"-org", "2", "orgs"},
+			contains: []string{"Mainnet", "Testnet", "Admin"},
+		},
+		"render": {
+			args:     []string{"render", "-o", output, "monitors", "Config checksum"},
+			contains: []string{output},
+		},
+		"render flags after arguments": {
+			args:     []string{"render", "monitors", "Config checksum", "-o", trailing},
+			contains: []string{trailing},
+		},
+		"query flags after arguments": {
+			args:     []string{"query", "config_crc32", "-time", "2022-04-15T05:20:00Z"},
+			contains: []string{"hub", "305419896"},
+		},
+	}
+
+	for name, tt := range tests {
+		var stdout, stderr bytes.Buffer
+
+		code := run(append([]string{"-addr", server.URL}, tt.args...), &stdout, &stderr)
+		if code != 0 {
+			t.Fatalf("%s: exit code %d: %s", name, code, stderr.String())
+		}
+
+		for _, s := range tt.contains {
+			if !strings.Contains(stdout.String(), s) {
+				t.Fatalf("%s: output has no %q:\n%s", name, s, stdout.String())
+			}
+		}
+	}
+
+	for _, file := range []string{output, trailing} {
+		if _, err := os.Stat(file); err != nil {
+			t.Fatalf("image is not written: %s", err)
+		}
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"-addr", "localhost", "unknown"},
+		{"-addr", "localhost", "panel", "monitors"},
+	} {
+		var stdout, stderr bytes.Buffer
+
+		if code := run(args, &stdout, &stderr); code != 2 {
+			t.Fatalf("%v: got exit code %d, want 2", args, code)
+		}
+	}
+}
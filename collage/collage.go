@@ -0,0 +1,358 @@
+// Package collage lays panel images out in a single PNG, e.g. all alerting panels of a daily summary.
+//
+// Panels are drawn with their titles and a border in the color of their alert state, either in
+// a grid or where the dashboard gridPos puts them. Only the standard image packages are used.
+package collage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+
+	grafana "github.com/lidofinance/grafana-monitors-client"
+)
+
+// Layout selects where panels are placed.
+type Layout int
+
+const (
+	// LayoutGrid places panels left to right in rows of WithColumns panels at their image size.
+	LayoutGrid Layout = iota
+	// LayoutDashboard places panels where their GridPos puts them on the dashboard and scales
+	// the images to fit. Panels without a position are appended below.
+	LayoutDashboard
+)
+
+const (
+	defaultColumns = 2
+
+	// dashboardColumns is the width of the Grafana grid
+	dashboardColumns = 24
+	// defaultColumnWidth and defaultRowHeight are the pixels of a grid unit in LayoutDashboard;
+	// rows are 30 pixels high in Grafana too
+	defaultColumnWidth = 50
+	defaultRowHeight   = 30
+	// defaultGridW and defaultGridH are the size of panels without a position, half the dashboard wide
+	defaultGridW = 12
+	defaultGridH = 8
+
+	margin      = 8
+	borderWidth = 4
+	textScale   = 2
+	captionPad  = 4
+	captionSize = glyphHeight*textScale + 2*captionPad
+)
+
+const (
+	alertingState = "alerting"
+	pendingState  = "pending"
+	noDataState   = "no_data"
+	okState       = "ok"
+)
+
+var (
+	// colors of the Grafana dark theme
+	defaultBackground = color.RGBA{R: 0x11, G: 0x12, B: 0x17, A: 0xff}
+	panelBackground   = color.RGBA{R: 0x18, G: 0x1b, B: 0x1f, A: 0xff}
+	captionColor      = color.RGBA{R: 0xcc, G: 0xcc, B: 0xdc, A: 0xff}
+
+	stateColors = map[string]color.Color{
+		alertingState: color.RGBA{R: 0xe0, G: 0x2f, B: 0x44, A: 0xff},
+		pendingState:  color.RGBA{R: 0xff, G: 0x98, B: 0x30, A: 0xff},
+		noDataState:   color.RGBA{R: 0x8e, G: 0x8e, B: 0x8e, A: 0xff},
+		okState:       color.RGBA{R: 0x73, G: 0xbf, B: 0x69, A: 0xff},
+	}
+)
+
+// Compositor lays panel images out in a single PNG.
+type Compositor struct {
+	layout      Layout
+	columns     int
+	columnWidth int
+	rowHeight   int
+	captions    bool
+	borders     bool
+	background  color.Color
+}
+
+type Option func(c *Compositor)
+
+// WithLayout sets the layout, LayoutGrid by default.
+func WithLayout(layout Layout) Option {
+	return func(c *Compositor) {
+		c.layout = layout
+	}
+}
+
+// WithColumns sets the number of panels per row of LayoutGrid, 2 by default.
+func WithColumns(n int) Option {
+	return func(c *Compositor) {
+		c.columns = n
+	}
+}
+
+// WithGridUnit sets the pixels of a dashboard grid column and row in LayoutDashboard, 50x30 by default.
+func WithGridUnit(columnWidth, rowHeight int) Option {
+	return func(c *Compositor) {
+		c.columnWidth = columnWidth
+		c.rowHeight = rowHeight
+	}
+}
+
+// WithCaptions draws panel titles above the images, on by default.
+func WithCaptions(captions bool) Option {
+	return func(c *Compositor) {
+		c.captions = captions
+	}
+}
+
+// WithBorders draws a border in the color of the panel alert state, on by default.
+// Panels without an alert get no border.
+func WithBorders(borders bool) Option {
+	return func(c *Compositor) {
+		c.borders = borders
+	}
+}
+
+// WithBackground sets the color between panels, the Grafana dark theme background by default.
+func WithBackground(background color.Color) Option {
+	return func(c *Compositor) {
+		c.background = background
+	}
+}
+
+// New creates a compositor with the grid layout, captions and borders unless options say otherwise.
+func New(opts ...Option) *Compositor {
+	c := &Compositor{
+		layout:      LayoutGrid,
+		columns:     defaultColumns,
+		columnWidth: defaultColumnWidth,
+		rowHeight:   defaultRowHeight,
+		captions:    true,
+		borders:     true,
+		background:  defaultBackground,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.columns <= 0 {
+		c.columns = defaultColumns
+	}
+
+	return c
+}
+
+// Render gets the image of every panel with GetPanelPicture and composes them.
+func (c *Compositor) Render(g grafana.Grafana, panels []grafana.Panel) ([]byte, error) {
+	images := make([][]byte, len(panels))
+
+	for i, p := range panels {
+		image, err := g.GetPanelPicture(p.Image)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get picture of panel %s: %w", p.Title, err)
+		}
+
+		images[i] = image
+	}
+
+	return c.Compose(panels, images)
+}
+
+// Compose lays out the PNG images of the panels, given in the same order, and encodes the result as PNG.
+// A nil image, e.g. of a panel RenderPanels failed to render, leaves an empty panel with its title.
+func (c *Compositor) Compose(panels []grafana.Panel, images [][]byte) ([]byte, error) {
+	if len(panels) != len(images) {
+		return nil, fmt.Errorf("got %d images for %d panels", len(images), len(panels))
+	}
+
+	if len(panels) == 0 {
+		return nil, errors.New("no panels to compose")
+	}
+
+	decoded := make([]image.Image, len(images))
+
+	for i, b := range images {
+		if b == nil {
+			continue
+		}
+
+		img, err := png.Decode(bytes.NewReader(b))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode picture of panel %s: %w", panels[i].Title, err)
+		}
+
+		decoded[i] = img
+	}
+
+	var cells []image.Rectangle
+
+	switch c.layout {
+	case LayoutDashboard:
+		cells = c.dashboardCells(panels)
+	default:
+		cells = c.gridCells(decoded)
+	}
+
+	var bounds image.Rectangle
+	for _, cell := range cells {
+		bounds = bounds.Union(cell)
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, bounds.Max.X+margin, bounds.Max.Y+margin))
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(c.background), image.Point{}, draw.Src)
+
+	for i, cell := range cells {
+		c.drawPanel(canvas, cell, panels[i], decoded[i])
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, canvas); err != nil {
+		return nil, fmt.Errorf("failed to encode collage: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// gridCells gives every panel a cell as large as the largest image.
+func (c *Compositor) gridCells(images []image.Image) []image.Rectangle {
+	var size image.Point
+
+	for _, img := range images {
+		if img == nil {
+			continue
+		}
+
+		if b := img.Bounds(); b.Dx() > size.X {
+			size.X = b.Dx()
+		}
+
+		if b := img.Bounds(); b.Dy() > size.Y {
+			size.Y = b.Dy()
+		}
+	}
+
+	size = size.Add(c.chrome())
+	cells := make([]image.Rectangle, len(images))
+
+	for i := range images {
+		col, row := i%c.columns, i/c.columns
+		min := image.Pt(margin+col*(size.X+margin), margin+row*(size.Y+margin))
+		cells[i] = image.Rectangle{Min: min, Max: min.Add(size)}
+	}
+
+	return cells
+}
+
+// dashboardCells places panels by their grid position, with the rows above the first panel left out.
+func (c *Compositor) dashboardCells(panels []grafana.Panel) []image.Rectangle {
+	positions := make([]grafana.GridPos, len(panels))
+	top, bottom := -1, 0
+
+	for i, p := range panels {
+		if p.GridPos == nil {
+			continue
+		}
+
+		positions[i] = *p.GridPos
+
+		if top < 0 || p.GridPos.Y < top {
+			top = p.GridPos.Y
+		}
+
+		if end := p.GridPos.Y + p.GridPos.H; end > bottom {
+			bottom = end
+		}
+	}
+
+	if top < 0 {
+		top = 0
+	}
+
+	unpositioned := 0
+
+	for i, p := range panels {
+		if p.GridPos != nil {
+			continue
+		}
+
+		perRow := dashboardColumns / defaultGridW
+		positions[i] = grafana.GridPos{
+			X: (unpositioned % perRow) * defaultGridW,
+			Y: bottom + (unpositioned/perRow)*defaultGridH,
+			W: defaultGridW,
+			H: defaultGridH,
+		}
+		unpositioned++
+	}
+
+	cells := make([]image.Rectangle, len(panels))
+
+	for i, pos := range positions {
+		min := image.Pt(margin+pos.X*c.columnWidth, margin+(pos.Y-top)*c.rowHeight)
+		size := image.Pt(pos.W*c.columnWidth-margin, pos.H*c.rowHeight-margin)
+		cells[i] = image.Rectangle{Min: min, Max: min.Add(size)}
+	}
+
+	return cells
+}
+
+// chrome is the space the border and caption add around an image.
+func (c *Compositor) chrome() image.Point {
+	p := image.Pt(2*borderWidth, 2*borderWidth)
+	if c.captions {
+		p.Y += captionSize
+	}
+
+	return p
+}
+
+func (c *Compositor) drawPanel(canvas *image.RGBA, cell image.Rectangle, p grafana.Panel, img image.Image) {
+	// alert states are lower case in the API but not in the UI
+	var border color.Color = panelBackground
+	if stateColor, ok := stateColors[strings.ToLower(p.Alert.State)]; ok && c.borders {
+		border = stateColor
+	}
+
+	draw.Draw(canvas, cell, image.NewUniform(border), image.Point{}, draw.Src)
+
+	inner := cell.Inset(borderWidth)
+	draw.Draw(canvas, inner, image.NewUniform(panelBackground), image.Point{}, draw.Src)
+
+	if c.captions {
+		drawText(canvas, inner.Min.Add(image.Pt(captionPad, captionPad)), p.Title, captionColor, textScale, inner.Dx()-2*captionPad)
+		inner.Min.Y += captionSize
+	}
+
+	if img == nil || inner.Empty() {
+		return
+	}
+
+	if c.layout == LayoutDashboard {
+		scale(canvas, inner, img)
+
+		return
+	}
+
+	draw.Draw(canvas, inner, img, img.Bounds().Min, draw.Over)
+}
+
+// scale draws src stretched over r with nearest-neighbor sampling.
+func scale(dst *image.RGBA, r image.Rectangle, src image.Image) {
+	sb := src.Bounds()
+
+	for y := 0; y < r.Dy(); y++ {
+		sy := sb.Min.Y + y*sb.Dy()/r.Dy()
+
+		for x := 0; x < r.Dx(); x++ {
+			sx := sb.Min.X + x*sb.Dx()/r.Dx()
+			dst.Set(r.Min.X+x, r.Min.Y+y, src.At(sx, sy))
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package collage
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	grafana "github.com/lidofinance/grafana-monitors-client"
+)
+
+var white = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+
+func testImage(t *testing.T, w, h int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, white)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func decode(t *testing.T, b []byte) image.Image {
+	t.Helper()
+
+	img, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return img
+}
+
+func sameColor(a, b color.Color) bool {
+	r1, g1, b1, a1 := a.RGBA()
+	r2, g2, b2, a2 := b.RGBA()
+
+	return r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2
+}
+
+func testPanels() []grafana.Panel {
+	return []grafana.Panel{
+		{ID: 2, Title: "Update global index", Image: "http://grafana/render/2", GridPos: &grafana.GridPos{X: 0, Y: 1, W: 12, H: 8}},
+		{ID: 3, Title: "Config checksum", Image: "http://grafana/render/3", GridPos: &grafana.GridPos{X: 12, Y: 1, W: 12, H: 8}},
+		{
+			ID:      4,
+			Title:   "Slashing: Jailed Validators",
+			Image:   "http://grafana/render/4",
+			Alert:   grafana.Alert{State: "Alerting"},
+			GridPos: &grafana.GridPos{X: 0, Y: 10, W: 24, H: 8},
+		},
+	}
+}
+
+func TestComposeGrid(t *testing.T) {
+	images := [][]byte{testImage(t, 100, 50), testImage(t, 100, 50), testImage(t, 80, 40)}
+
+	b, err := New().Compose(testPanels(), images)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := decode(t, b)
+
+	// two columns of 100x50 images with borders, captions and margins
+	if size := img.Bounds().Size(); size != image.Pt(240, 188) {
+		t.Fatalf("wrong collage size %v", size)
+	}
+
+	// the alerting panel is the first of the second row
+	if !sameColor(img.At(8, 98), stateColors[alertingState]) {
+		t.Fatalf("alerting panel has no red border: %v", img.At(8, 98))
+	}
+
+	if !sameColor(img.At(8, 8), panelBackground) {
+		t.Fatalf("panel without alert has a border: %v", img.At(8, 8))
+	}
+
+	// the image is below the caption
+	if !sameColor(img.At(12, 12+captionSize), white) {
+		t.Fatal("image is not drawn")
+	}
+
+	// the caption is drawn above the image
+	caption := false
+
+	for x := 12; x < 112 && !caption; x++ {
+		for y := 12; y < 12+captionSize; y++ {
+			if sameColor(img.At(x, y), captionColor) {
+				caption = true
+
+				break
+			}
+		}
+	}
+
+	if !caption {
+		t.Fatal("caption is not drawn")
+	}
+}
+
+func TestComposeDashboardLayout(t *testing.T) {
+	images := [][]byte{testImage(t, 100, 50), nil, testImage(t, 100, 50)}
+
+	b, err := New(WithLayout(LayoutDashboard), WithCaptions(false), WithBorders(false)).Compose(testPanels(), images)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := decode(t, b)
+
+	// 24 columns of 50 pixels, rows 1 to 17 of 30 pixels
+	if size := img.Bounds().Size(); size != image.Pt(1208, 518) {
+		t.Fatalf("wrong collage size %v", size)
+	}
+
+	// images are scaled to their cells
+	if !sameColor(img.At(1190, 500), white) {
+		t.Fatal("image is not scaled to the panel width")
+	}
+
+	if !sameColor(img.At(700, 100), panelBackground) {
+		t.Fatal("panel without image is not empty")
+	}
+
+	if !sameColor(img.At(8, 278), panelBackground) {
+		t.Fatal("border is drawn with borders off")
+	}
+}
+
+func TestComposeErrors(t *testing.T) {
+	c := New()
+
+	if _, err := c.Compose(testPanels(), [][]byte{testImage(t, 10, 10)}); err == nil {
+		t.Fatal("expected error for missing images")
+	}
+
+	if _, err := c.Compose(nil, nil); err == nil {
+		t.Fatal("expected error for no panels")
+	}
+
+	if _, err := c.Compose(testPanels()[:1], [][]byte{[]byte("not a png")}); err == nil {
+		t.Fatal("expected error for invalid image")
+	}
+}
+
+func TestRender(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	g := grafana.NewMockGrafana(ctrl)
+	g.EXPECT().GetPanelPicture("http://grafana/render/2").Return(testImage(t, 100, 50), nil)
+	g.EXPECT().GetPanelPicture("http://grafana/render/3").Return(testImage(t, 100, 50), nil)
+	g.EXPECT().GetPanelPicture("http://grafana/render/4").Return(testImage(t, 100, 50), nil)
+
+	b, err := New(WithColumns(3)).Render(g, testPanels())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size := decode(t, b).Bounds().Size(); size != image.Pt(356, 98) {
+		t.Fatalf("wrong collage size %v", size)
+	}
+
+	g.EXPECT().GetPanelPicture(gomock.Any()).Return(nil, errors.New("status code is 500"))
+
+	if _, err = New().Render(g, testPanels()); err == nil {
+		t.Fatal("expected error for failed picture")
+	}
+}
@@ -0,0 +1,41 @@
+package grafana
+
+import (
+	"context"
+	"strings"
+)
+
+// directAPI returns the Prometheus API the datasource is mapped to with WithPrometheusURLs.
+// References the mapping does not name directly are resolved through the Grafana datasource list,
+// so a mapping by name also covers panels that reference the datasource by uid and vice versa.
+// The list may need admin rights; when it can't be read such references are queried through Grafana.
+func (c *client) directAPI(ctx context.Context, ref datasourceRef) (prometheusAPI, bool) {
+	if len(c.prometheusURLs) == 0 {
+		return prometheusAPI{}, false
+	}
+
+	for _, key := range []string{ref.UID, ref.Name} {
+		if key == "" || strings.HasPrefix(key, "$") {
+			continue
+		}
+
+		if u, ok := c.prometheusURLs[key]; ok {
+			return prometheusAPI{url: u}, true
+		}
+	}
+
+	info, err := c.lookupDatasource(ctx, ref)
+	if err != nil {
+		c.logger.Log(ctx, LogLevelDebug, "failed to look up datasource, querying through grafana", "error", err)
+
+		return prometheusAPI{}, false
+	}
+
+	for _, key := range []string{info.UID, info.Name} {
+		if u, ok := c.prometheusURLs[key]; ok && key != "" {
+			return prometheusAPI{url: u}, true
+		}
+	}
+
+	return prometheusAPI{}, false
+}
@@ -0,0 +1,130 @@
+package grafana
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDirectPrometheusQueries(t *testing.T) {
+	prometheus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != instantQueryPath {
+			t.Errorf("unexpected prometheus path %s", r.URL.Path)
+		}
+
+		if r.Header.Get(authHeader) != "" {
+			t.Error("grafana token leaked to prometheus")
+		}
+
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1650000000,"7"]}]}}`))
+	}))
+	defer prometheus.Close()
+
+	var proxied int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case datasourcesListPath:
+			w.Write([]byte(`[{"id":1,"uid":"prom","name":"Prometheus","type":"prometheus","isDefault":true},
+				{"id":2,"uid":"other","name":"Other","type":"prometheus"}]`))
+		case datasourcesPath + instantQueryPath:
+			proxied++
+			w.Write([]byte(`{"status":"success","data":{"resultType":"scalar","result":[1650000000,"3"]}}`))
+		default:
+			t.Errorf("unexpected grafana path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	g := NewGrafana(server.URL, "token", timeout, ImageAttributes{},
+		WithPrometheusURLs(map[string]string{"Prometheus": prometheus.URL + "/"})).(*grafana)
+
+	values, err := g.client.currentValues(context.Background(), []expr{
+		{Query: "a", LegendFormat: "a", Datasource: datasourceRef{UID: "prom"}},
+		{Query: "b", LegendFormat: "b", Datasource: datasourceRef{UID: "other"}},
+		{Query: "c", LegendFormat: "c"},
+	}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if values[0].Values[0].Value != "7" || values[1].Values[0].Value != "3" || values[2].Values[0].Value != "7" {
+		t.Fatalf("queries are routed wrong: %+v", values)
+	}
+
+	if proxied != 1 {
+		t.Fatalf("got %d proxied queries, want 1", proxied)
+	}
+
+	if _, err = g.Query(context.Background(), "up", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if proxied != 1 {
+		t.Fatal("default datasource query went through grafana")
+	}
+}
+
+func TestDirectPrometheusWithoutDatasourceList(t *testing.T) {
+	prometheus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1650000000,"7"]}]}}`))
+	}))
+	defer prometheus.Close()
+
+	var proxied, listed int
+
+	// a Viewer token may not list datasources
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case datasourcesListPath:
+			listed++
+			w.WriteHeader(http.StatusForbidden)
+		case datasourcesPath + instantQueryPath:
+			proxied++
+			w.Write([]byte(`{"status":"success","data":{"resultType":"scalar","result":[1650000000,"3"]}}`))
+		default:
+			t.Errorf("unexpected grafana path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	logger := &recordingLogger{}
+
+	g := NewGrafana(server.URL, "token", timeout, ImageAttributes{},
+		WithPrometheusURLs(map[string]string{"Prometheus": prometheus.URL}), WithLogger(logger)).(*grafana)
+
+	values, err := g.client.currentValues(context.Background(), []expr{
+		{Query: "a", LegendFormat: "a", Datasource: datasourceRef{Name: "Prometheus"}},
+		{Query: "b", LegendFormat: "b", Datasource: datasourceRef{UID: "prom"}},
+	}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if values[0].Values[0].Value != "7" || values[1].Values[0].Value != "3" {
+		t.Fatalf("queries are routed wrong: %+v", values)
+	}
+
+	if _, err = g.Query(context.Background(), "up", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if proxied != 2 {
+		t.Fatalf("got %d proxied queries, want 2", proxied)
+	}
+
+	// the failed list is not requested again by every query
+	if _, err = g.client.currentValues(context.Background(), []expr{{Query: "b", Datasource: datasourceRef{UID: "prom"}}}, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if listed != 1 {
+		t.Fatalf("datasources listed %d times, want 1", listed)
+	}
+
+	if warnings := logger.find("failed to list datasources"); len(warnings) != 1 || warnings[0].level != LogLevelWarn {
+		t.Fatalf("expected a single warning, got %+v", warnings)
+	}
+}
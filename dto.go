@@ -1,9 +1,28 @@
 package grafana
 
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
 const (
 	rowPanelType = "row"
 )
 
+const (
+	vectorResultType = "vector"
+	matrixResultType = "matrix"
+	scalarResultType = "scalar"
+	stringResultType = "string"
+
+	// labelMetricName is the metric label used as a series name for "{{label}}" legends
+	labelMetricName = "label"
+)
+
 type alertStateDTO struct {
 	ID            int    `json:"id"`
 	DashboardID   int    `json:"dashboardId"`
@@ -35,6 +54,10 @@ type dashboardDTO struct {
 		ID     int     `json:"id"`
 		Panels []panel `json:"panels"`
 		UID    string  `json:"uid"`
+		Time   struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		} `json:"time"`
 	} `json:"dashboard"`
 }
 
@@ -58,101 +81,684 @@ type panel struct {
 			} `json:"reducer"`
 			Type string `json:"type"`
 		} `json:"conditions"`
-		ExecutionErrorState string        `json:"executionErrorState"`
-		For                 string        `json:"for"`
-		Frequency           string        `json:"frequency"`
-		Handler             int64         `json:"handler"`
-		Message             string        `json:"message"`
-		Name                string        `json:"name"`
-		NoDataState         string        `json:"noDataState"`
-		Notifications       []interface{} `json:"notifications"`
+		ExecutionErrorState string               `json:"executionErrorState"`
+		For                 string               `json:"for"`
+		Frequency           string               `json:"frequency"`
+		Handler             int64                `json:"handler"`
+		Message             string               `json:"message"`
+		Name                string               `json:"name"`
+		NoDataState         string               `json:"noDataState"`
+		Notifications       []notificationRefDTO `json:"notifications"`
 	} `json:"alert"`
-	Targets []struct {
-		Expr         string `json:"expr"`
-		LegendFormat string `json:"legendFormat"`
+	Datasource    datasourceRef  `json:"datasource"`
+	FieldConfig   fieldConfigDTO `json:"fieldConfig"`
+	GridPos       *GridPos       `json:"gridPos"`
+	Interval      string         `json:"interval"`
+	MaxDataPoints int64          `json:"maxDataPoints"`
+	Options       struct {
+		ReduceOptions *struct {
+			Calcs  []string `json:"calcs"`
+			Fields string   `json:"fields"`
+			Values bool     `json:"values"`
+			Limit  int      `json:"limit"`
+		} `json:"reduceOptions"`
+	} `json:"options"`
+	TimeFrom  string `json:"timeFrom"`
+	TimeShift string `json:"timeShift"`
+	Targets   []struct {
+		RefID        string        `json:"refId"`
+		Datasource   datasourceRef `json:"datasource"`
+		Expr         string        `json:"expr"`
+		IntervalMs   int64         `json:"intervalMs"`
+		LegendFormat string        `json:"legendFormat"`
 	} `json:"targets"`
 	Title string `json:"title"`
 	Type  string `json:"type"`
+	// LibraryPanel references a library element holding the rest of the panel
+	LibraryPanel *LibraryPanel `json:"libraryPanel"`
+	// Panels holds the panels of a collapsed row
+	Panels []panel `json:"panels"`
+}
+
+type fieldConfigDTO struct {
+	Defaults  fieldDefaultsDTO   `json:"defaults"`
+	Overrides []fieldOverrideDTO `json:"overrides"`
+}
+
+type fieldDefaultsDTO struct {
+	DisplayName string            `json:"displayName"`
+	Unit        string            `json:"unit"`
+	Decimals    *int              `json:"decimals"`
+	Min         *float64          `json:"min"`
+	Max         *float64          `json:"max"`
+	Thresholds  thresholdsDTO     `json:"thresholds"`
+	Mappings    []valueMappingDTO `json:"mappings"`
+}
+
+type thresholdsDTO struct {
+	Mode  string `json:"mode"`
+	Steps []struct {
+		Color string   `json:"color"`
+		Value *float64 `json:"value"`
+	} `json:"steps"`
+}
+
+// valueMappingDTO options depend on the type: value mappings are keyed by the value,
+// the others hold a single result.
+type valueMappingDTO struct {
+	Type    string          `json:"type"`
+	Options json.RawMessage `json:"options"`
+}
+
+type mappingResultDTO struct {
+	Text  string `json:"text"`
+	Color string `json:"color"`
+	Index int    `json:"index"`
+}
+
+type fieldOverrideDTO struct {
+	Matcher struct {
+		ID      string          `json:"id"`
+		Options json.RawMessage `json:"options"`
+	} `json:"matcher"`
+	Properties []struct {
+		ID    string          `json:"id"`
+		Value json.RawMessage `json:"value"`
+	} `json:"properties"`
+}
+
+func (f *fieldConfigDTO) ToFieldConfig() fieldConfig {
+	return fieldConfig{
+		DisplayName: f.Defaults.DisplayName,
+		Unit:        f.Defaults.Unit,
+		Decimals:    f.Defaults.Decimals,
+		Min:         f.Defaults.Min,
+		Max:         f.Defaults.Max,
+		Thresholds:  f.Defaults.Thresholds.ToThresholds(),
+		Mappings:    toValueMappings(f.Defaults.Mappings),
+	}
+}
+
+func (t *thresholdsDTO) ToThresholds() Thresholds {
+	thresholds := Thresholds{
+		Mode: ThresholdsMode(t.Mode),
+	}
+
+	if thresholds.Mode == "" && len(t.Steps) > 0 {
+		thresholds.Mode = ThresholdsModeAbsolute
+	}
+
+	for i, s := range t.Steps {
+		step := ThresholdStep{
+			Color: s.Color,
+			Value: s.Value,
+		}
+
+		// the base step is saved as null, or as -Infinity which JSON can't hold
+		if i == 0 {
+			step.Value = nil
+		}
+
+		thresholds.Steps = append(thresholds.Steps, step)
+	}
+
+	return thresholds
+}
+
+// toValueMappings converts the mappings in the order Grafana applies them. Malformed mappings are skipped.
+func toValueMappings(mappings []valueMappingDTO) []valueMapping {
+	var result []valueMapping
+
+	for _, m := range mappings {
+		switch m.Type {
+		case valueMappingType:
+			var options map[string]mappingResultDTO
+			if err := json.Unmarshal(m.Options, &options); err != nil {
+				continue
+			}
+
+			values := make([]valueMapping, 0, len(options))
+			for value, r := range options {
+				values = append(values, valueMapping{
+					Type:   m.Type,
+					Value:  value,
+					Result: mappingResult{Text: r.Text, Color: r.Color},
+					index:  r.Index,
+				})
+			}
+
+			sort.Slice(values, func(i, j int) bool {
+				if values[i].index != values[j].index {
+					return values[i].index < values[j].index
+				}
+
+				return values[i].Value < values[j].Value
+			})
+
+			result = append(result, values...)
+		case rangeMappingType, regexMappingType, specialMappingType:
+			var options struct {
+				From    *float64         `json:"from"`
+				To      *float64         `json:"to"`
+				Pattern string           `json:"pattern"`
+				Match   string           `json:"match"`
+				Result  mappingResultDTO `json:"result"`
+			}
+
+			if err := json.Unmarshal(m.Options, &options); err != nil {
+				continue
+			}
+
+			mapping := valueMapping{
+				Type:   m.Type,
+				From:   options.From,
+				To:     options.To,
+				Match:  options.Match,
+				Result: mappingResult{Text: options.Result.Text, Color: options.Result.Color},
+			}
+
+			if m.Type == regexMappingType {
+				pattern, err := compileGrafanaRegexp(options.Pattern)
+				if err != nil {
+					continue
+				}
+
+				mapping.Pattern = pattern
+			}
+
+			result = append(result, mapping)
+		}
+	}
+
+	return result
+}
+
+// ToFieldOverrides converts the overrides with supported matchers. Unknown matchers and properties are skipped.
+func (f *fieldConfigDTO) ToFieldOverrides() []fieldOverride {
+	var overrides []fieldOverride
+
+	for _, o := range f.Overrides {
+		var option string
+		if err := json.Unmarshal(o.Matcher.Options, &option); err != nil {
+			continue
+		}
+
+		override := fieldOverride{
+			Matcher: fieldMatcher{ID: o.Matcher.ID, Option: option},
+		}
+
+		if o.Matcher.ID == byRegexpMatcher {
+			pattern, err := compileGrafanaRegexp(option)
+			if err != nil {
+				continue
+			}
+
+			override.Matcher.pattern = pattern
+		}
+
+		for _, p := range o.Properties {
+			if property, ok := toFieldProperty(p.ID, p.Value); ok {
+				override.Properties = append(override.Properties, property)
+			}
+		}
+
+		overrides = append(overrides, override)
+	}
+
+	return overrides
+}
+
+func toFieldProperty(id string, value json.RawMessage) (func(c *fieldConfig), bool) {
+	switch id {
+	case "displayName":
+		var name string
+		if json.Unmarshal(value, &name) != nil {
+			return nil, false
+		}
+
+		return func(c *fieldConfig) { c.DisplayName = name }, true
+	case "unit":
+		var unit string
+		if json.Unmarshal(value, &unit) != nil {
+			return nil, false
+		}
+
+		return func(c *fieldConfig) { c.Unit = unit }, true
+	case "decimals":
+		var decimals *int
+		if json.Unmarshal(value, &decimals) != nil {
+			return nil, false
+		}
+
+		return func(c *fieldConfig) { c.Decimals = decimals }, true
+	case "min", "max":
+		var limit *float64
+		if json.Unmarshal(value, &limit) != nil {
+			return nil, false
+		}
+
+		if id == "min" {
+			return func(c *fieldConfig) { c.Min = limit }, true
+		}
+
+		return func(c *fieldConfig) { c.Max = limit }, true
+	case "thresholds":
+		var thresholds thresholdsDTO
+		if json.Unmarshal(value, &thresholds) != nil {
+			return nil, false
+		}
+
+		t := thresholds.ToThresholds()
+
+		return func(c *fieldConfig) { c.Thresholds = t }, true
+	case "mappings":
+		var mappings []valueMappingDTO
+		if json.Unmarshal(value, &mappings) != nil {
+			return nil, false
+		}
+
+		m := toValueMappings(mappings)
+
+		return func(c *fieldConfig) { c.Mappings = m }, true
+	}
+
+	return nil, false
+}
+
+// datasourceRef is either a {"uid", "type"} object or, in older dashboards, a datasource name.
+type datasourceRef struct {
+	UID  string `json:"uid,omitempty"`
+	Type string `json:"type,omitempty"`
+	Name string `json:"-"`
+}
+
+func (d *datasourceRef) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		return json.Unmarshal(b, &d.Name)
+	}
+
+	type plain datasourceRef
+
+	return json.Unmarshal(b, (*plain)(d))
+}
+
+func (d datasourceRef) IsEmpty() bool {
+	return d.UID == "" && d.Name == ""
 }
 
 func (d *dashboardDTO) Data() (result dashboardData) {
 	result.ID = d.Dashboard.ID
 
+	var row string
+
 	for _, p := range d.Dashboard.Panels {
-		if p.Type == rowPanelType {
+		if p.Type != rowPanelType {
+			result.Panels = append(result.Panels, d.panelData(p, row))
+
 			continue
 		}
 
-		panel := panelData{
-			ID:    p.ID,
-			Title: p.Title,
+		row = p.Title
+
+		for _, nested := range p.Panels {
+			result.Panels = append(result.Panels, d.panelData(nested, row))
 		}
+	}
 
-		for _, t := range p.Targets {
-			panel.Exprs = append(panel.Exprs, expr{
-				Query:        t.Expr,
-				LegendFormat: t.LegendFormat,
-			})
+	return result
+}
+
+func (d *dashboardDTO) panelData(p panel, row string) panelData {
+	data := p.Data(row)
+	data.TimeRange.From = d.Dashboard.Time.From
+	data.TimeRange.To = d.Dashboard.Time.To
+
+	return data
+}
+
+func (p *panel) Data(row string) panelData {
+	panel := panelData{
+		ID:             p.ID,
+		LibraryPanel:   p.LibraryPanel,
+		Title:          p.Title,
+		Type:           p.Type,
+		Row:            row,
+		GridPos:        p.GridPos,
+		FieldConfig:    p.FieldConfig.ToFieldConfig(),
+		FieldOverrides: p.FieldConfig.ToFieldOverrides(),
+		TimeRange: timeRange{
+			PanelFrom:  p.TimeFrom,
+			PanelShift: p.TimeShift,
+		},
+	}
+
+	if r := p.Options.ReduceOptions; r != nil {
+		panel.Reduce = &reduceOptions{
+			Calcs:  r.Calcs,
+			Fields: r.Fields,
+			Values: r.Values,
+			Limit:  r.Limit,
+		}
+	}
+
+	// panel interval is a minimum interval such as "30s"; unparsable values are left to Grafana
+	panelInterval, _ := time.ParseDuration(p.Interval)
+
+	for i, t := range p.Targets {
+		e := expr{
+			RefID:         t.RefID,
+			Query:         t.Expr,
+			LegendFormat:  t.LegendFormat,
+			Datasource:    t.Datasource,
+			IntervalMs:    t.IntervalMs,
+			MaxDataPoints: p.MaxDataPoints,
 		}
 
-		panel.Alert = Alert{
-			Name: p.Alert.Name,
+		if e.RefID == "" {
+			e.RefID = string(rune('A' + i))
 		}
 
-		for _, c := range p.Alert.Conditions {
-			panel.Alert.Conditions = append(panel.Alert.Conditions, Condition{
-				Type:   c.Evaluator.Type,
-				Values: c.Evaluator.Params,
-			})
+		if e.Datasource.IsEmpty() {
+			e.Datasource = p.Datasource
+		}
 
+		if e.IntervalMs == 0 {
+			e.IntervalMs = panelInterval.Milliseconds()
 		}
 
-		result.Panels = append(result.Panels, panel)
+		panel.Exprs = append(panel.Exprs, e)
 	}
 
-	return result
+	panel.Alert = Alert{
+		Name: p.Alert.Name,
+	}
+
+	for _, n := range p.Alert.Notifications {
+		panel.Alert.Notifications = append(panel.Alert.Notifications, NotificationRef{UID: n.UID, ID: n.ID})
+	}
+
+	for _, c := range p.Alert.Conditions {
+		panel.Alert.Conditions = append(panel.Alert.Conditions, Condition{
+			Type:   c.Evaluator.Type,
+			Values: c.Evaluator.Params,
+		})
+
+	}
+
+	return panel
 }
 
 type datasourceDTO struct {
 	Status string `json:"status"`
 	Data   struct {
-		ResultType string `json:"resultType"`
-		Result     []struct {
-			Metric struct {
-				Name     string `json:"__name__"`
-				Instance string `json:"instance"`
-				Job      string `json:"job"`
-				Label    string `json:"label"`
-			} `json:"metric"`
-			Values [][]interface{} `json:"values"`
-		} `json:"result"`
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
 	} `json:"data"`
-	Error string `json:"error"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+}
+
+type vectorResultDTO []struct {
+	Metric map[string]string `json:"metric"`
+	Value  samplePairDTO     `json:"value"`
+}
+
+type matrixResultDTO []struct {
+	Metric map[string]string `json:"metric"`
+	Values []samplePairDTO   `json:"values"`
 }
 
-func (d datasourceDTO) ToLabelValues() []LabelValue {
+// samplePairDTO is a Prometheus sample: unix timestamp in seconds followed by the value as a string.
+type samplePairDTO []interface{}
+
+func (s samplePairDTO) Point() (Point, error) {
+	if len(s) != 2 {
+		return Point{}, fmt.Errorf("unexpected sample length %d", len(s))
+	}
+
+	ts, ok := s[0].(float64)
+	if !ok {
+		return Point{}, fmt.Errorf("unexpected sample timestamp %v", s[0])
+	}
+
+	value, ok := s[1].(string)
+	if !ok {
+		return Point{}, fmt.Errorf("unexpected sample value %v", s[1])
+	}
+
+	return Point{
+		Time:  time.UnixMilli(int64(math.Round(ts * 1000))),
+		Value: value,
+	}, nil
+}
+
+func (d datasourceDTO) ToLabelValues() ([]LabelValue, error) {
 	var currentLabelValues []LabelValue
 
-	for _, r := range d.Data.Result {
-		if len(r.Values) == 0 {
-			return nil
+	switch d.Data.ResultType {
+	case vectorResultType:
+		var vector vectorResultDTO
+		if err := json.Unmarshal(d.Data.Result, &vector); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal vector result: %w", err)
 		}
 
-		values := r.Values[0]
+		for _, r := range vector {
+			point, err := r.Value.Point()
+			if err != nil {
+				return nil, err
+			}
+
+			currentLabelValues = append(currentLabelValues, LabelValue{
+				Label: r.Metric[labelMetricName],
+				Value: point.Value,
+			})
+		}
+	case matrixResultType:
+		series, err := d.ToSeries()
+		if err != nil {
+			return nil, err
+		}
 
-		// first element in array is unix timestamp, second element is current value in string type
-		if len(values) == 2 {
-			value, ok := values[1].(string)
-			if !ok {
-				return nil
+		// the latest point of every series is its current value
+		for _, s := range series {
+			if len(s.Points) == 0 {
+				continue
 			}
 
 			currentLabelValues = append(currentLabelValues, LabelValue{
-				Label: r.Metric.Label,
-				Value: value,
+				Label: s.Label,
+				Value: s.Points[len(s.Points)-1].Value,
 			})
 		}
+	case scalarResultType, stringResultType:
+		var sample samplePairDTO
+		if err := json.Unmarshal(d.Data.Result, &sample); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s result: %w", d.Data.ResultType, err)
+		}
+
+		point, err := sample.Point()
+		if err != nil {
+			return nil, err
+		}
+
+		currentLabelValues = append(currentLabelValues, LabelValue{
+			Value: point.Value,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported result type %q", d.Data.ResultType)
+	}
+
+	return currentLabelValues, nil
+}
+
+func (d datasourceDTO) ToSeries() ([]Series, error) {
+	if d.Data.ResultType != matrixResultType {
+		return nil, fmt.Errorf("unexpected result type %q, want %q", d.Data.ResultType, matrixResultType)
+	}
+
+	var matrix matrixResultDTO
+	if err := json.Unmarshal(d.Data.Result, &matrix); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal matrix result: %w", err)
+	}
+
+	series := make([]Series, 0, len(matrix))
+
+	for _, r := range matrix {
+		s := Series{
+			Label:  r.Metric[labelMetricName],
+			Points: make([]Point, 0, len(r.Values)),
+		}
+
+		for _, v := range r.Values {
+			point, err := v.Point()
+			if err != nil {
+				return nil, err
+			}
+
+			s.Points = append(s.Points, point)
+		}
+
+		series = append(series, s)
+	}
+
+	return series, nil
+}
+
+type datasourceInfoDTO struct {
+	ID        int    `json:"id"`
+	UID       string `json:"uid"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	URL       string `json:"url"`
+	IsDefault bool   `json:"isDefault"`
+}
+
+type dsQueryRequestDTO struct {
+	Queries []dsQueryDTO `json:"queries"`
+	From    string       `json:"from"`
+	To      string       `json:"to"`
+}
+
+type dsQueryDTO struct {
+	RefID         string        `json:"refId"`
+	Datasource    datasourceRef `json:"datasource"`
+	Expr          string        `json:"expr"`
+	Instant       bool          `json:"instant"`
+	Range         bool          `json:"range"`
+	IntervalMs    int64         `json:"intervalMs"`
+	MaxDataPoints int64         `json:"maxDataPoints"`
+}
+
+type dsQueryResponseDTO struct {
+	Results map[string]dsQueryResultDTO `json:"results"`
+	Message string                      `json:"message"`
+}
+
+type dsQueryResultDTO struct {
+	Status int            `json:"status"`
+	Error  string         `json:"error"`
+	Frames []dataFrameDTO `json:"frames"`
+}
+
+type dataFrameDTO struct {
+	Schema struct {
+		Name   string `json:"name"`
+		RefID  string `json:"refId"`
+		Fields []struct {
+			Name   string            `json:"name"`
+			Type   string            `json:"type"`
+			Labels map[string]string `json:"labels"`
+		} `json:"fields"`
+	} `json:"schema"`
+	Data struct {
+		Values [][]interface{} `json:"values"`
+	} `json:"data"`
+}
+
+const (
+	timeFieldType   = "time"
+	numberFieldType = "number"
+	stringFieldType = "string"
+)
+
+// ToSeries converts every number or string field of the frames to a series indexed by the frame time field.
+func (r dsQueryResultDTO) ToSeries() []Series {
+	var series []Series
+
+	for _, f := range r.Frames {
+		timeIndex := -1
+
+		for i, field := range f.Schema.Fields {
+			if field.Type == timeFieldType {
+				timeIndex = i
+
+				break
+			}
+		}
+
+		for i, field := range f.Schema.Fields {
+			if field.Type != numberFieldType && field.Type != stringFieldType {
+				continue
+			}
+
+			s := Series{Label: field.Labels[labelMetricName]}
+
+			if i >= len(f.Data.Values) {
+				series = append(series, s)
+
+				continue
+			}
+
+			for j, v := range f.Data.Values[i] {
+				value, ok := frameValue(v)
+				if !ok {
+					continue
+				}
+
+				var point = Point{Value: value}
+
+				if timeIndex >= 0 && timeIndex < len(f.Data.Values) && j < len(f.Data.Values[timeIndex]) {
+					if ts, ok := f.Data.Values[timeIndex][j].(float64); ok {
+						point.Time = time.UnixMilli(int64(ts))
+					}
+				}
+
+				s.Points = append(s.Points, point)
+			}
+
+			series = append(series, s)
+		}
+	}
+
+	return series
+}
+
+// ToLabelValues returns the latest value of every series.
+func (r dsQueryResultDTO) ToLabelValues() []LabelValue {
+	var currentLabelValues []LabelValue
+
+	for _, s := range r.ToSeries() {
+		if len(s.Points) == 0 {
+			continue
+		}
+
+		currentLabelValues = append(currentLabelValues, LabelValue{
+			Label: s.Label,
+			Value: s.Points[len(s.Points)-1].Value,
+		})
 	}
 
 	return currentLabelValues
 }
+
+// frameValue formats a data frame value the same way Prometheus formats sample values.
+func frameValue(v interface{}) (string, bool) {
+	switch value := v.(type) {
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	case string:
+		return value, true
+	}
+
+	return "", false
+}
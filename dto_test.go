@@ -0,0 +1,176 @@
+package grafana
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDatasourceToLabelValues(t *testing.T) {
+	tests := map[string]struct {
+		body   string
+		values []LabelValue
+	}{
+		"vector": {
+			body: `{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"label":"a"},"value":[1650000000.5,"1"]},
+				{"metric":{"label":"b"},"value":[1650000000.5,"2"]}]}}`,
+			values: []LabelValue{{Label: "a", Value: "1"}, {Label: "b", Value: "2"}},
+		},
+		"matrix": {
+			body: `{"status":"success","data":{"resultType":"matrix","result":[
+				{"metric":{"label":"a"},"values":[[1650000000,"1"],[1650000010,"3"]]}]}}`,
+			values: []LabelValue{{Label: "a", Value: "3"}},
+		},
+		"scalar": {
+			body:   `{"status":"success","data":{"resultType":"scalar","result":[1650000000,"42"]}}`,
+			values: []LabelValue{{Value: "42"}},
+		},
+		"string": {
+			body:   `{"status":"success","data":{"resultType":"string","result":[1650000000,"up"]}}`,
+			values: []LabelValue{{Value: "up"}},
+		},
+		"empty vector": {
+			body: `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+		},
+	}
+
+	for name, tt := range tests {
+		var datasource datasourceDTO
+		if err := json.Unmarshal([]byte(tt.body), &datasource); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		values, err := datasource.ToLabelValues()
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		if len(values) != len(tt.values) {
+			t.Fatalf("%s: got %d values, want %d", name, len(values), len(tt.values))
+		}
+
+		for i := range values {
+			if values[i] != tt.values[i] {
+				t.Fatalf("%s: got %+v, want %+v", name, values[i], tt.values[i])
+			}
+		}
+	}
+}
+
+func TestDatasourceToSeries(t *testing.T) {
+	var datasource datasourceDTO
+
+	body := `{"status":"success","data":{"resultType":"matrix","result":[
+		{"metric":{"label":"a"},"values":[[1650000000,"1"],[1650000010.25,"3"]]}]}}`
+	if err := json.Unmarshal([]byte(body), &datasource); err != nil {
+		t.Fatal(err)
+	}
+
+	series, err := datasource.ToSeries()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(series) != 1 || len(series[0].Points) != 2 {
+		t.Fatalf("unexpected series %+v", series)
+	}
+
+	if series[0].Points[1].Time.UnixMilli() != 1650000010250 {
+		t.Fatalf("wrong timestamp %s", series[0].Points[1].Time)
+	}
+
+	datasource.Data.ResultType = vectorResultType
+	if _, err = datasource.ToSeries(); err == nil {
+		t.Fatal("expected error for vector result")
+	}
+}
+
+func TestDsQueryResultToSeries(t *testing.T) {
+	var response dsQueryResponseDTO
+
+	body := `{"results":{"A":{"status":200,"frames":[
+		{"schema":{"refId":"A","fields":[{"name":"Time","type":"time"},{"name":"Value","type":"number","labels":{"label":"a"}}]},
+		 "data":{"values":[[1650000000000,1650000010000],[1,2.5]]}},
+		{"schema":{"refId":"A","fields":[{"name":"Time","type":"time"},{"name":"Value","type":"number","labels":{"label":"b"}}]},
+		 "data":{"values":[[1650000000000],[null]]}}]}}}`
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	series := response.Results["A"].ToSeries()
+	if len(series) != 2 {
+		t.Fatalf("got %d series, want 2", len(series))
+	}
+
+	if len(series[0].Points) != 2 || series[0].Points[1].Value != "2.5" {
+		t.Fatalf("unexpected series %+v", series[0])
+	}
+
+	if series[0].Points[1].Time.UnixMilli() != 1650000010000 {
+		t.Fatalf("wrong timestamp %s", series[0].Points[1].Time)
+	}
+
+	values := response.Results["A"].ToLabelValues()
+	if len(values) != 1 || values[0] != (LabelValue{Label: "a", Value: "2.5"}) {
+		t.Fatalf("unexpected values %+v", values)
+	}
+}
+
+func TestDashboardDatasourceRefs(t *testing.T) {
+	var dashboard dashboardDTO
+
+	body := `{"dashboard":{"id":1,"panels":[
+		{"id":1,"type":"graph","datasource":"Prometheus","interval":"30s","targets":[{"expr":"up"},{"expr":"down"}]},
+		{"id":2,"type":"timeseries","datasource":{"type":"prometheus","uid":"abc"},"maxDataPoints":500,
+		 "targets":[{"refId":"Q","expr":"up","datasource":{"uid":"def"}}]}]}}`
+	if err := json.Unmarshal([]byte(body), &dashboard); err != nil {
+		t.Fatal(err)
+	}
+
+	data := dashboard.Data()
+
+	first := data.Panels[0].Exprs
+	if first[0].RefID != "A" || first[1].RefID != "B" {
+		t.Fatalf("refIds are not assigned: %+v", first)
+	}
+
+	if first[0].Datasource.Name != "Prometheus" || first[0].IntervalMs != 30000 {
+		t.Fatalf("panel datasource and interval are not inherited: %+v", first[0])
+	}
+
+	second := data.Panels[1].Exprs[0]
+	if second.RefID != "Q" || second.Datasource.UID != "def" || second.MaxDataPoints != 500 {
+		t.Fatalf("unexpected expr %+v", second)
+	}
+}
+
+func TestDashboardThresholds(t *testing.T) {
+	var dashboard dashboardDTO
+
+	body := `{"dashboard":{"id":1,"panels":[
+		{"id":1,"type":"stat","fieldConfig":{"defaults":{"min":0,"max":200,"thresholds":{"mode":"percentage",
+		 "steps":[{"color":"green","value":null},{"color":"red","value":80}]}}}},
+		{"id":2,"type":"graph"}]}}`
+	if err := json.Unmarshal([]byte(body), &dashboard); err != nil {
+		t.Fatal(err)
+	}
+
+	data := dashboard.Data()
+
+	stat := data.Panels[0]
+	if stat.FieldConfig.Thresholds.Mode != ThresholdsModePercentage || len(stat.FieldConfig.Thresholds.Steps) != 2 {
+		t.Fatalf("unexpected thresholds %+v", stat.FieldConfig.Thresholds)
+	}
+
+	if stat.FieldConfig.Thresholds.Steps[0].Value != nil || *stat.FieldConfig.Thresholds.Steps[1].Value != 80 || stat.FieldConfig.Thresholds.Steps[1].Color != "red" {
+		t.Fatalf("unexpected steps %+v", stat.FieldConfig.Thresholds.Steps)
+	}
+
+	if *stat.FieldConfig.Min != 0 || *stat.FieldConfig.Max != 200 {
+		t.Fatalf("unexpected min %v and max %v", *stat.FieldConfig.Min, *stat.FieldConfig.Max)
+	}
+
+	if graph := data.Panels[1]; len(graph.FieldConfig.Thresholds.Steps) != 0 || graph.FieldConfig.Thresholds.Mode != "" {
+		t.Fatalf("graph panel has thresholds %+v", graph.FieldConfig.Thresholds)
+	}
+}
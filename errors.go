@@ -0,0 +1,51 @@
+package grafana
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PanelError is a panel, or one of its queries, that could not be evaluated.
+type PanelError struct {
+	PanelID int
+	Title   string
+	// Query is the failed query, empty when the panel failed as a whole
+	Query string
+	Err   error
+}
+
+func (e *PanelError) Error() string {
+	if e.Query == "" {
+		return fmt.Sprintf("panel %d %q: %s", e.PanelID, e.Title, e.Err)
+	}
+
+	return fmt.Sprintf("panel %d %q query %s: %s", e.PanelID, e.Title, e.Query, e.Err)
+}
+
+func (e *PanelError) Unwrap() error {
+	return e.Err
+}
+
+// PanelsError is returned along with the panels in best-effort mode when some of them failed.
+// The failed panels are still returned with their Error set.
+type PanelsError struct {
+	DashboardUID string
+	Errors       []*PanelError
+}
+
+func (e *PanelsError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+
+	return fmt.Sprintf("%d panel errors in dashboard %s: %s", len(e.Errors), e.DashboardUID, strings.Join(msgs, "; "))
+}
+
+// IsPartial reports whether err only means that some panels failed, so the returned panels can be used.
+func IsPartial(err error) bool {
+	var partial *PanelsError
+
+	return errors.As(err, &partial)
+}
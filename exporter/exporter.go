@@ -0,0 +1,278 @@
+// Package exporter exposes Grafana alert states and panel values as Prometheus metrics.
+//
+// Every scrape calls Panels for the configured dashboards, so the metrics are as fresh as the
+// dashboards themselves:
+//
+//	grafana_panel_alert_state{dashboard,panel,state}   1 for the current state of the panel alert, 0 for the others
+//	grafana_panel_value{dashboard,panel,label}         current values of the panel queries
+//	grafana_exporter_query_success{dashboard}          whether the last scrape of the dashboard succeeded
+//	grafana_exporter_query_duration_seconds{dashboard} how long the last scrape of the dashboard took
+//	grafana_exporter_query_errors_total{dashboard}     failed scrapes of the dashboard
+//	grafana_exporter_panel_errors{dashboard}           panels that failed in the last scrape of the dashboard
+//
+// A dashboard scrape fails when the dashboard can't be read. With grafana.WithPartialResults the
+// panels that did return values are exported and the failed ones are only counted.
+//
+// Exporter is an http.Handler serving the metrics and a prometheus.Collector for registries that
+// already serve other metrics.
+package exporter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	grafana "github.com/lidofinance/grafana-monitors-client"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	dashboardLabel = "dashboard"
+	panelLabel     = "panel"
+	stateLabel     = "state"
+	labelLabel     = "label"
+
+	// scrapeTimeoutHeader carries the scrape_timeout of the Prometheus job
+	scrapeTimeoutHeader = "X-Prometheus-Scrape-Timeout-Seconds"
+)
+
+var (
+	alertStateDesc = prometheus.NewDesc("grafana_panel_alert_state",
+		"State of the panel alert, 1 for the current state.", []string{dashboardLabel, panelLabel, stateLabel}, nil)
+	panelValueDesc = prometheus.NewDesc("grafana_panel_value",
+		"Current value of the panel queries.", []string{dashboardLabel, panelLabel, labelLabel}, nil)
+	querySuccessDesc = prometheus.NewDesc("grafana_exporter_query_success",
+		"Whether the last scrape of the dashboard succeeded.", []string{dashboardLabel}, nil)
+	queryDurationDesc = prometheus.NewDesc("grafana_exporter_query_duration_seconds",
+		"Duration of the last scrape of the dashboard.", []string{dashboardLabel}, nil)
+	queryErrorsDesc = prometheus.NewDesc("grafana_exporter_query_errors_total",
+		"Failed scrapes of the dashboard.", []string{dashboardLabel}, nil)
+	panelErrorsDesc = prometheus.NewDesc("grafana_exporter_panel_errors",
+		"Panels that failed in the last scrape of the dashboard.", []string{dashboardLabel}, nil)
+)
+
+// alertStates are exported for every panel with an alert so that a state change flips a 0 to 1
+// instead of creating a new series.
+var alertStates = []string{"alerting", "pending", "no_data", "ok", "paused"}
+
+// Exporter collects metrics of dashboards on every scrape.
+type Exporter struct {
+	grafana    grafana.Grafana
+	timeout    time.Duration
+	dashboards []string
+
+	mu     sync.Mutex
+	errors map[string]float64
+}
+
+// New creates an exporter of the given dashboards. Timeout bounds a whole scrape, use the request
+// timeout of the Grafana client; a shorter scrape timeout sent by Prometheus takes precedence.
+func New(g grafana.Grafana, timeout time.Duration, dashboardUIDs ...string) *Exporter {
+	errors := make(map[string]float64, len(dashboardUIDs))
+	for _, uid := range dashboardUIDs {
+		errors[uid] = 0
+	}
+
+	return &Exporter{
+		grafana:    g,
+		timeout:    timeout,
+		dashboards: dashboardUIDs,
+		errors:     errors,
+	}
+}
+
+type dashboardResult struct {
+	uid      string
+	panels   []grafana.Panel
+	err      error
+	duration time.Duration
+}
+
+func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{alertStateDesc, panelValueDesc, querySuccessDesc, queryDurationDesc, queryErrorsDesc, panelErrorsDesc} {
+		ch <- desc
+	}
+}
+
+// Collect scrapes all dashboards concurrently, bounded by the exporter timeout.
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := withTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	e.collect(ctx, ch)
+}
+
+func (e *Exporter) collect(ctx context.Context, ch chan<- prometheus.Metric) {
+	results := make([]dashboardResult, len(e.dashboards))
+
+	var wg sync.WaitGroup
+
+	for i, uid := range e.dashboards {
+		wg.Add(1)
+
+		go func(i int, uid string) {
+			defer wg.Done()
+
+			start := time.Now()
+			panels, err := e.grafana.Panels(ctx, uid)
+
+			results[i] = dashboardResult{
+				uid:      uid,
+				panels:   panels,
+				err:      err,
+				duration: time.Since(start),
+			}
+		}(i, uid)
+	}
+
+	wg.Wait()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, r := range results {
+		send(ch, queryDurationDesc, prometheus.GaugeValue, r.duration.Seconds(), r.uid)
+
+		// with partial results the dashboard is read, only some of its panels failed
+		if r.err != nil && !grafana.IsPartial(r.err) {
+			e.errors[r.uid]++
+
+			send(ch, querySuccessDesc, prometheus.GaugeValue, 0, r.uid)
+
+			continue
+		}
+
+		send(ch, querySuccessDesc, prometheus.GaugeValue, 1, r.uid)
+		send(ch, panelErrorsDesc, prometheus.GaugeValue, float64(failedPanels(r.err)), r.uid)
+
+		sendAlertStates(ch, r.uid, r.panels)
+		sendPanelValues(ch, r.uid, r.panels)
+	}
+
+	for _, uid := range e.dashboards {
+		send(ch, queryErrorsDesc, prometheus.CounterValue, e.errors[uid], uid)
+	}
+}
+
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := withTimeout(r.Context(), e.scrapeTimeout(r))
+	defer cancel()
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(scrape{exporter: e, ctx: ctx})
+
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError}).ServeHTTP(w, r)
+}
+
+// scrape collects the metrics of one request with its context.
+type scrape struct {
+	exporter *Exporter
+	ctx      context.Context
+}
+
+func (s scrape) Describe(ch chan<- *prometheus.Desc) {
+	s.exporter.Describe(ch)
+}
+
+func (s scrape) Collect(ch chan<- prometheus.Metric) {
+	s.exporter.collect(s.ctx, ch)
+}
+
+func (e *Exporter) scrapeTimeout(r *http.Request) time.Duration {
+	timeout := e.timeout
+
+	if seconds, err := strconv.ParseFloat(r.Header.Get(scrapeTimeoutHeader), 64); err == nil && seconds > 0 {
+		if scrape := time.Duration(seconds * float64(time.Second)); timeout <= 0 || scrape < timeout {
+			timeout = scrape
+		}
+	}
+
+	return timeout
+}
+
+// withTimeout does not bound the context when the timeout is not positive.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// failedPanels counts the panels of a partial result; a panel may have several failed queries.
+func failedPanels(err error) int {
+	var partial *grafana.PanelsError
+	if !errors.As(err, &partial) {
+		return 0
+	}
+
+	panels := make(map[int]bool, len(partial.Errors))
+	for _, e := range partial.Errors {
+		panels[e.PanelID] = true
+	}
+
+	return len(panels)
+}
+
+// send reports a metric whose label values can't be exported as an invalid one, which fails only
+// that metric of the scrape.
+func send(ch chan<- prometheus.Metric, desc *prometheus.Desc, valueType prometheus.ValueType, value float64, labelValues ...string) {
+	m, err := prometheus.NewConstMetric(desc, valueType, value, labelValues...)
+	if err != nil {
+		m = prometheus.NewInvalidMetric(desc, err)
+	}
+
+	ch <- m
+}
+
+func sendAlertStates(ch chan<- prometheus.Metric, dashboard string, panels []grafana.Panel) {
+	seen := make(map[string]bool)
+
+	for _, p := range panels {
+		if p.Alert.Name == "" || seen[p.Title] {
+			continue
+		}
+
+		seen[p.Title] = true
+		current := strings.ToLower(p.Alert.State)
+
+		for _, state := range alertStates {
+			var value float64
+			if state == current {
+				value = 1
+			}
+
+			send(ch, alertStateDesc, prometheus.GaugeValue, value, dashboard, p.Title, state)
+		}
+	}
+}
+
+// sendPanelValues skips values that are not numbers and repeated series, which Prometheus would reject.
+func sendPanelValues(ch chan<- prometheus.Metric, dashboard string, panels []grafana.Panel) {
+	seen := make(map[[2]string]bool)
+
+	for _, p := range panels {
+		for _, cv := range p.CurrentValues {
+			for _, v := range cv.Values {
+				value, err := strconv.ParseFloat(v.Value, 64)
+				if err != nil {
+					continue
+				}
+
+				key := [2]string{p.Title, v.Label}
+				if seen[key] {
+					continue
+				}
+
+				seen[key] = true
+
+				send(ch, panelValueDesc, prometheus.GaugeValue, value, dashboard, p.Title, v.Label)
+			}
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	grafana "github.com/lidofinance/grafana-monitors-client"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var testPanels = []grafana.Panel{
+	{
+		Title: "Slashing: Jailed Validators",
+		Alert: grafana.Alert{Name: "Jailed validators alert", State: "alerting"},
+		CurrentValues: []grafana.CurrentValue{
+			{Values: []grafana.LabelValue{{Label: "jailed", Value: "2"}}},
+		},
+	},
+	{
+		Title: `Config "crc32"`,
+		CurrentValues: []grafana.CurrentValue{
+			{Values: []grafana.LabelValue{{Label: "hub", Value: "3735928559"}, {Label: "hub", Value: "1"}, {Label: "text", Value: "up"}}},
+		},
+	},
+}
+
+func TestExporter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	g := grafana.NewMockGrafana(ctrl)
+	g.EXPECT().Panels(gomock.Any(), "monitors").Return(testPanels, nil).Times(2)
+	g.EXPECT().Panels(gomock.Any(), "broken").Return(nil, errors.New("status code is 500")).Times(2)
+
+	e := New(g, time.Second, "monitors", "broken")
+
+	var body string
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+			t.Fatalf("unexpected content type %s", w.Header().Get("Content-Type"))
+		}
+
+		body = w.Body.String()
+	}
+
+	for _, line := range []string{
+		"# TYPE grafana_panel_alert_state gauge",
+		`grafana_panel_alert_state{dashboard="monitors",panel="Slashing: Jailed Validators",state="alerting"} 1`,
+		`grafana_panel_alert_state{dashboard="monitors",panel="Slashing: Jailed Validators",state="ok"} 0`,
+		`grafana_panel_value{dashboard="monitors",label="jailed",panel="Slashing: Jailed Validators"} 2`,
+		`grafana_panel_value{dashboard="monitors",label="hub",panel="Config \"crc32\""} 3.735928559e+09`,
+		`grafana_exporter_query_success{dashboard="monitors"} 1`,
+		`grafana_exporter_query_success{dashboard="broken"} 0`,
+		"# TYPE grafana_exporter_query_errors_total counter",
+		`grafana_exporter_query_errors_total{dashboard="broken"} 2`,
+		`grafana_exporter_query_errors_total{dashboard="monitors"} 0`,
+		`grafana_exporter_query_duration_seconds{dashboard="monitors"}`,
+	} {
+		if !strings.Contains(body, line+"\n") && !strings.Contains(body, line+" ") {
+			t.Fatalf("metrics have no %q:\n%s", line, body)
+		}
+	}
+
+	if strings.Contains(body, `label="text"`) || strings.Count(body, `label="hub"`) != 1 {
+		t.Fatalf("non-numeric or repeated values are exported:\n%s", body)
+	}
+}
+
+func TestScrapeTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	g := grafana.NewMockGrafana(ctrl)
+	g.EXPECT().Panels(gomock.Any(), "monitors").DoAndReturn(func(ctx context.Context, uid string, filter ...string) ([]grafana.Panel, error) {
+		deadline, ok := ctx.Deadline()
+		if !ok || time.Until(deadline) > 500*time.Millisecond {
+			t.Errorf("scrape is not bounded by the prometheus scrape timeout")
+		}
+
+		return testPanels, nil
+	})
+
+	e := New(g, time.Minute, "monitors")
+
+	r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	r.Header.Set(scrapeTimeoutHeader, "0.5")
+
+	e.ServeHTTP(httptest.NewRecorder(), r)
+}
+
+func TestPartialResults(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	g := grafana.NewMockGrafana(ctrl)
+
+	// a panel with two failed queries is a single failed panel
+	partial := &grafana.PanelsError{DashboardUID: "monitors", Errors: []*grafana.PanelError{
+		{PanelID: 5, Title: "Broken", Query: "a", Err: errors.New("status code is 500")},
+		{PanelID: 5, Title: "Broken", Query: "b", Err: errors.New("status code is 500")},
+	}}
+	g.EXPECT().Panels(gomock.Any(), "monitors").Return(append(testPanels, grafana.Panel{Title: "Broken", Error: partial.Errors[0].Error()}), partial)
+
+	e := New(g, time.Second, "monitors")
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := w.Body.String()
+
+	for _, line := range []string{
+		`grafana_panel_value{dashboard="monitors",label="jailed",panel="Slashing: Jailed Validators"} 2`,
+		`grafana_exporter_query_success{dashboard="monitors"} 1`,
+		`grafana_exporter_query_errors_total{dashboard="monitors"} 0`,
+		`grafana_exporter_panel_errors{dashboard="monitors"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Fatalf("metrics have no %q:\n%s", line, body)
+		}
+	}
+}
+
+func TestCollector(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	g := grafana.NewMockGrafana(ctrl)
+	g.EXPECT().Panels(gomock.Any(), "monitors").Return(testPanels, nil)
+	g.EXPECT().Panels(gomock.Any(), "broken").Return(nil, errors.New("status code is 500"))
+
+	registry := prometheus.NewPedanticRegistry()
+	if err := registry.Register(New(g, time.Second, "monitors", "broken")); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP grafana_exporter_query_errors_total Failed scrapes of the dashboard.
+# TYPE grafana_exporter_query_errors_total counter
+grafana_exporter_query_errors_total{dashboard="broken"} 1
+grafana_exporter_query_errors_total{dashboard="monitors"} 0
+# HELP grafana_panel_value Current value of the panel queries.
+# TYPE grafana_panel_value gauge
+grafana_panel_value{dashboard="monitors",label="hub",panel="Config \"crc32\""} 3.735928559e+09
+grafana_panel_value{dashboard="monitors",label="jailed",panel="Slashing: Jailed Validators"} 2
+`
+
+	err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "grafana_exporter_query_errors_total", "grafana_panel_value")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
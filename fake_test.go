@@ -0,0 +1,137 @@
+package grafana
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	fakeToken        = "Bearer fake-token"
+	fakeDashboardUID = "monitors"
+	fakeDashboardID  = "1"
+)
+
+// fakeGrafana serves dashboard, alerts, datasource proxy and render endpoints from testdata.
+// Instant queries are answered from queries.json and range queries from ranges.json.
+// Any path can be overridden with handle to test error paths.
+type fakeGrafana struct {
+	*httptest.Server
+
+	t       *testing.T
+	queries map[string]json.RawMessage
+	ranges  map[string]json.RawMessage
+
+	mu        sync.Mutex
+	overrides map[string]http.HandlerFunc
+}
+
+func newFakeGrafana(t *testing.T) *fakeGrafana {
+	f := &fakeGrafana{
+		t:         t,
+		overrides: make(map[string]http.HandlerFunc),
+	}
+
+	if err := json.Unmarshal(readFixture(t, "queries.json"), &f.queries); err != nil {
+		t.Fatalf("failed to unmarshal queries fixture: %s", err)
+	}
+
+	if err := json.Unmarshal(readFixture(t, "ranges.json"), &f.ranges); err != nil {
+		t.Fatalf("failed to unmarshal ranges fixture: %s", err)
+	}
+
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+// newFakeGrafanaClient returns a Grafana instance pointed at a fresh fake.
+func newFakeGrafanaClient(t *testing.T, opts ...Option) (*fakeGrafana, Grafana) {
+	f := newFakeGrafana(t)
+
+	return f, NewGrafana(f.URL, fakeToken, timeout, ImageAttributes{
+		Height:   500,
+		Width:    1000,
+		Timezone: "Europe/Moscow",
+	}, opts...)
+}
+
+func (f *fakeGrafana) handle(path string, h http.HandlerFunc) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.overrides[path] = h
+}
+
+func (f *fakeGrafana) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(authHeader) != fakeToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message":"Unauthorized"}`))
+
+		return
+	}
+
+	f.mu.Lock()
+	override, ok := f.overrides[r.URL.Path]
+	f.mu.Unlock()
+
+	if ok {
+		override(w, r)
+
+		return
+	}
+
+	switch {
+	case r.URL.Path == dashboardPath+fakeDashboardUID:
+		w.Write(readFixture(f.t, "dashboard.json"))
+	case r.URL.Path == alertsPath:
+		if r.URL.Query().Get("dashboardId") != fakeDashboardID {
+			w.Write([]byte(`[]`))
+
+			return
+		}
+
+		w.Write(readFixture(f.t, "alerts.json"))
+	case r.URL.Path == datasourcesPath+instantQueryPath:
+		result, ok := f.queries[r.URL.Query().Get("query")]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"unknown query"}`))
+
+			return
+		}
+
+		w.Write(result)
+	case r.URL.Path == datasourcesPath+rangeQueryPath:
+		result, ok := f.ranges[r.URL.Query().Get("query")]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"unknown query"}`))
+
+			return
+		}
+
+		w.Write(result)
+	case strings.HasPrefix(r.URL.Path, "/render/d-solo/"+fakeDashboardUID+"/"):
+		w.Header().Set(contentTypeHeader, "image/png")
+		w.Write(readFixture(f.t, "panel.png"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"Not found"}`))
+	}
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	body, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("failed to read fixture %s: %s", name, err)
+	}
+
+	return body
+}
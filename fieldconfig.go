@@ -0,0 +1,259 @@
+package grafana
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Value mapping types of fieldConfig mappings.
+const (
+	valueMappingType   = "value"
+	rangeMappingType   = "range"
+	regexMappingType   = "regex"
+	specialMappingType = "special"
+)
+
+// Special values matched by special value mappings.
+const (
+	specialMatchNull    = "null"
+	specialMatchNaN     = "nan"
+	specialMatchNullNaN = "null+nan"
+	specialMatchTrue    = "true"
+	specialMatchFalse   = "false"
+	specialMatchEmpty   = "empty"
+)
+
+// Matchers of fieldConfig overrides.
+const (
+	byNameMatcher       = "byName"
+	byRegexpMatcher     = "byRegexp"
+	byFrameRefIDMatcher = "byFrameRefID"
+)
+
+// fieldConfig is how the values of a field are displayed: fieldConfig.defaults with the matching overrides applied.
+type fieldConfig struct {
+	DisplayName string
+	Unit        string
+	Decimals    *int
+	// Min and Max bound the field for percentage thresholds, nil means the range of the values
+	Min        *float64
+	Max        *float64
+	Thresholds Thresholds
+	Mappings   []valueMapping
+}
+
+type fieldOverride struct {
+	Matcher    fieldMatcher
+	Properties []func(c *fieldConfig)
+}
+
+type fieldMatcher struct {
+	ID     string
+	Option string
+
+	pattern *regexp.Regexp
+}
+
+// Match reports whether the override applies to a series of the query with the refId.
+func (m fieldMatcher) Match(refID, name string) bool {
+	switch m.ID {
+	case byNameMatcher:
+		return name == m.Option
+	case byRegexpMatcher:
+		return m.pattern != nil && m.pattern.MatchString(name)
+	case byFrameRefIDMatcher:
+		return refID == m.Option
+	}
+
+	return false
+}
+
+type valueMapping struct {
+	Type string
+	// Value is the value of a value mapping
+	Value string
+	// From and To bound a range mapping, nil is unbounded
+	From *float64
+	To   *float64
+	// Pattern is the expression of a regex mapping
+	Pattern *regexp.Regexp
+	// Match is the special value of a special mapping
+	Match  string
+	Result mappingResult
+
+	index int
+}
+
+type mappingResult struct {
+	Text  string
+	Color string
+}
+
+// Map returns the result of the mapping for a value. The text of a regex mapping may refer to its groups.
+func (m valueMapping) Map(value string) (mappingResult, bool) {
+	number, err := strconv.ParseFloat(value, 64)
+	isNumber := err == nil
+
+	switch m.Type {
+	case valueMappingType:
+		if value == m.Value {
+			return m.Result, true
+		}
+
+		if key, err := strconv.ParseFloat(m.Value, 64); err == nil && isNumber && key == number {
+			return m.Result, true
+		}
+	case rangeMappingType:
+		if !isNumber || math.IsNaN(number) || (m.From == nil && m.To == nil) {
+			return mappingResult{}, false
+		}
+
+		if (m.From == nil || number >= *m.From) && (m.To == nil || number <= *m.To) {
+			return m.Result, true
+		}
+	case regexMappingType:
+		if m.Pattern == nil {
+			return mappingResult{}, false
+		}
+
+		match := m.Pattern.FindStringSubmatchIndex(value)
+		if match == nil {
+			return mappingResult{}, false
+		}
+
+		// like String.replace in Grafana, only the first match is replaced
+		result := m.Result
+		if result.Text != "" {
+			text := m.Pattern.ExpandString(nil, result.Text, value, match)
+			result.Text = value[:match[0]] + string(text) + value[match[1]:]
+		}
+
+		return result, true
+	case specialMappingType:
+		isNaN := isNumber && math.IsNaN(number)
+		isNull := strings.EqualFold(value, specialMatchNull)
+
+		switch m.Match {
+		case specialMatchNaN:
+			if isNaN {
+				return m.Result, true
+			}
+		case specialMatchNull:
+			if isNull {
+				return m.Result, true
+			}
+		case specialMatchNullNaN:
+			if isNull || isNaN {
+				return m.Result, true
+			}
+		case specialMatchTrue, specialMatchFalse:
+			if strings.EqualFold(value, m.Match) {
+				return m.Result, true
+			}
+		case specialMatchEmpty:
+			if value == "" {
+				return m.Result, true
+			}
+		}
+	}
+
+	return mappingResult{}, false
+}
+
+// compileGrafanaRegexp accepts both plain patterns and the /pattern/flags form of the Grafana editor.
+func compileGrafanaRegexp(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") {
+		if end := strings.LastIndex(pattern, "/"); end > 0 {
+			if flags := pattern[end+1:]; strings.Contains(flags, "i") {
+				return regexp.Compile("(?i)" + pattern[1:end])
+			}
+
+			return regexp.Compile(pattern[1:end])
+		}
+	}
+
+	return regexp.Compile(pattern)
+}
+
+// fieldConfig returns the display config of a series: the defaults with every matching override
+// applied in order, so later overrides win.
+func (p panelData) fieldConfig(refID, name string) fieldConfig {
+	config := p.FieldConfig
+	for _, o := range p.FieldOverrides {
+		if !o.Matcher.Match(refID, name) {
+			continue
+		}
+
+		for _, apply := range o.Properties {
+			apply(&config)
+		}
+	}
+
+	return config
+}
+
+// display sets how every value is shown in the panel: its threshold step, formatted value,
+// mapped text and color, and display name. Without min or max the range of the panel values
+// bounds percentage thresholds, as Grafana does.
+func (p panelData) display(currentValues []CurrentValue) {
+	min, max := math.Inf(1), math.Inf(-1)
+
+	for _, cv := range currentValues {
+		for _, v := range cv.Values {
+			if f, err := strconv.ParseFloat(v.Value, 64); err == nil && !math.IsNaN(f) {
+				min, max = math.Min(min, f), math.Max(max, f)
+			}
+		}
+	}
+
+	for i := range currentValues {
+		var refID string
+		if i < len(p.Exprs) {
+			refID = p.Exprs[i].RefID
+		}
+
+		for j := range currentValues[i].Values {
+			v := &currentValues[i].Values[j]
+			config := p.fieldConfig(refID, v.Label)
+
+			if f, err := strconv.ParseFloat(v.Value, 64); err == nil {
+				fieldMin, fieldMax := min, max
+				if config.Min != nil {
+					fieldMin = *config.Min
+				}
+
+				if config.Max != nil {
+					fieldMax = *config.Max
+				}
+
+				v.Threshold = config.Thresholds.Step(f, fieldMin, fieldMax)
+				v.FormattedValue = FormatValue(f, config.Unit, config.Decimals)
+			}
+
+			for _, m := range config.Mappings {
+				result, ok := m.Map(v.Value)
+				if !ok {
+					continue
+				}
+
+				if result.Text != "" {
+					v.FormattedValue = result.Text
+				}
+
+				v.Color = result.Color
+
+				break
+			}
+
+			if v.Color == "" && v.Threshold != nil {
+				v.Color = v.Threshold.Color
+			}
+
+			if config.DisplayName != "" {
+				v.Label = config.DisplayName
+			}
+		}
+	}
+}
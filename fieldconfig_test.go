@@ -0,0 +1,161 @@
+package grafana
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+)
+
+func TestDisplayMappingsAndOverrides(t *testing.T) {
+	var dashboard dashboardDTO
+
+	body := `{"dashboard":{"id":1,"panels":[{"id":1,"type":"stat",
+		"targets":[{"refId":"A","expr":"up"},{"refId":"B","expr":"jailed"}],
+		"fieldConfig":{
+			"defaults":{
+				"unit":"short",
+				"thresholds":{"mode":"absolute","steps":[{"color":"green","value":null},{"color":"red","value":5}]},
+				"mappings":[
+					{"type":"value","options":{"1":{"text":"Up","color":"green","index":1},"0":{"text":"Down","color":"red","index":0}}},
+					{"type":"range","options":{"from":null,"to":-1,"result":{"text":"Jailed","color":"purple","index":2}}},
+					{"type":"regex","options":{"pattern":"/^v(\\d+)$/i","result":{"text":"version $1","index":3}}},
+					{"type":"special","options":{"match":"nan","result":{"text":"No data","index":4}}}
+				]
+			},
+			"overrides":[
+				{"matcher":{"id":"byName","options":"hub"},"properties":[{"id":"displayName","value":"Hub"},{"id":"mappings","value":[]},{"id":"unit","value":"percent"}]},
+				{"matcher":{"id":"byRegexp","options":"/^rew/"},"properties":[{"id":"decimals","value":2},{"id":"mappings","value":[]}]},
+				{"matcher":{"id":"byFrameRefID","options":"B"},"properties":[{"id":"thresholds","value":{"mode":"absolute","steps":[{"color":"blue","value":null}]}}]},
+				{"matcher":{"id":"byType","options":"number"},"properties":[{"id":"unit","value":"bytes"}]}
+			]
+		}}]}}`
+	if err := json.Unmarshal([]byte(body), &dashboard); err != nil {
+		t.Fatal(err)
+	}
+
+	p := dashboard.Data().Panels[0]
+
+	if len(p.FieldConfig.Mappings) != 5 || p.FieldConfig.Mappings[0].Value != "0" {
+		t.Fatalf("mappings are not parsed in order: %+v", p.FieldConfig.Mappings)
+	}
+
+	if len(p.FieldOverrides) != 4 {
+		t.Fatalf("got %d overrides, want 4", len(p.FieldOverrides))
+	}
+
+	values := []CurrentValue{
+		{Query: "up", Values: []LabelValue{
+			{Label: "node", Value: "1"},
+			{Label: "db", Value: "0"},
+			{Label: "hub", Value: "42"},
+			{Label: "reward", Value: "7"},
+			{Label: "release", Value: "V12"},
+			{Label: "lag", Value: "NaN"},
+			{Label: "supply", Value: "12000"},
+		}},
+		{Query: "jailed", Values: []LabelValue{
+			{Label: "validator", Value: "-3"},
+			{Label: "other", Value: "8"},
+		}},
+	}
+
+	p.display(values)
+
+	want := [][]LabelValue{
+		{
+			{Label: "node", Value: "1", FormattedValue: "Up", Color: "green"},
+			{Label: "db", Value: "0", FormattedValue: "Down", Color: "red"},
+			{Label: "Hub", Value: "42", FormattedValue: "42%", Color: "red"},
+			{Label: "reward", Value: "7", FormattedValue: "7.00", Color: "red"},
+			{Label: "release", Value: "V12", FormattedValue: "version 12"},
+			{Label: "lag", Value: "NaN", FormattedValue: "No data"},
+			{Label: "supply", Value: "12000", FormattedValue: "12 K", Color: "red"},
+		},
+		{
+			{Label: "validator", Value: "-3", FormattedValue: "Jailed", Color: "purple"},
+			{Label: "other", Value: "8", FormattedValue: "8", Color: "blue"},
+		},
+	}
+
+	for i := range want {
+		for j, w := range want[i] {
+			got := values[i].Values[j]
+			got.Threshold = nil
+
+			if got != w {
+				t.Errorf("got %+v, want %+v", got, w)
+			}
+		}
+	}
+}
+
+func TestCompileGrafanaRegexp(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		match   bool
+	}{
+		{"^up$", "up", true},
+		{"/^UP$/i", "up", true},
+		{"/^UP$/", "up", false},
+		{"a/b", "a/b", true},
+	}
+
+	for _, tt := range tests {
+		re, err := compileGrafanaRegexp(tt.pattern)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.pattern, err)
+		}
+
+		if re.MatchString(tt.value) != tt.match {
+			t.Errorf("%s matching %s is %v", tt.pattern, tt.value, !tt.match)
+		}
+	}
+}
+
+func TestSpecialMapping(t *testing.T) {
+	tests := []struct {
+		match string
+		value string
+		ok    bool
+	}{
+		{specialMatchNull, "null", true},
+		{specialMatchNull, "NaN", false},
+		{specialMatchNaN, "NaN", true},
+		{specialMatchNaN, "null", false},
+		{specialMatchNullNaN, "null", true},
+		{specialMatchNullNaN, "NaN", true},
+		{specialMatchNullNaN, "0", false},
+		{specialMatchEmpty, "", true},
+		{specialMatchTrue, "true", true},
+	}
+
+	for _, tt := range tests {
+		m := valueMapping{Type: specialMappingType, Match: tt.match, Result: mappingResult{Text: "No data"}}
+
+		if _, ok := m.Map(tt.value); ok != tt.ok {
+			t.Errorf("%s matching %q is %v", tt.match, tt.value, ok)
+		}
+	}
+}
+
+func TestRegexMappingReplacesFirstMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		text    string
+		value   string
+		want    string
+	}{
+		{`(\d)`, "<$1>", "a1b2", "a<1>b2"},
+		{`^v(\d+)$`, "version $1", "v12", "version 12"},
+		{`o`, "0", "foo", "f0o"},
+	}
+
+	for _, tt := range tests {
+		m := valueMapping{Type: regexMappingType, Pattern: regexp.MustCompile(tt.pattern), Result: mappingResult{Text: tt.text}}
+
+		if got, ok := m.Map(tt.value); !ok || got.Text != tt.want {
+			t.Errorf("%s mapping %q is %q %v, want %q", tt.pattern, tt.value, got.Text, ok, tt.want)
+		}
+	}
+}
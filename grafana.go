@@ -5,7 +5,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
@@ -15,85 +20,266 @@ const (
 
 type Grafana interface {
 	Panels(ctx context.Context, dashboardUid string, filterPanelNames ...string) ([]Panel, error)
+	SelectPanels(ctx context.Context, dashboardUID string, selector Selector) ([]Panel, error)
+	PanelByID(ctx context.Context, dashboardUID string, id int) (*Panel, error)
 	GetPanelPicture(url string) ([]byte, error)
+	RenderPanels(ctx context.Context, panels []Panel, attrs ImageAttributes, from, to time.Time) ([][]byte, error)
 	GetGrafanaPanel(panelName string, dashboardID string) (*Panel, error)
+	Query(ctx context.Context, query string, at time.Time) ([]LabelValue, error)
+	QueryRange(ctx context.Context, query string, from, to time.Time, step time.Duration) ([]Series, error)
+	CreateSnapshot(ctx context.Context, dashboardUID string, from, to time.Time, expires time.Duration) (*Snapshot, error)
+	ListSnapshots(ctx context.Context) ([]Snapshot, error)
+	DeleteSnapshot(ctx context.Context, key string) error
+	CreateAnnotation(ctx context.Context, a Annotation) (int64, error)
+	UpdateAnnotation(ctx context.Context, a Annotation) error
+	DeleteAnnotation(ctx context.Context, id int64) error
+	Annotations(ctx context.Context, q AnnotationQuery) ([]Annotation, error)
+	Orgs(ctx context.Context) ([]Org, error)
+	NotificationChannels(ctx context.Context) ([]NotificationChannel, error)
+	TestNotificationChannel(ctx context.Context, channel NotificationChannel) error
+	ContactPoints(ctx context.Context) ([]ContactPoint, error)
+	TestContactPoint(ctx context.Context, point ContactPoint) error
+	AlertRoutes(ctx context.Context, dashboardUID string) ([]AlertRoute, error)
 }
 
 type grafana struct {
-	client *client
-	attrs  ImageAttributes
+	client    *client
+	attrs     ImageAttributes
+	telemetry telemetryConfig
+
+	partialResults bool
+
+	renderConcurrency int
+	renderBucket      time.Duration
+	imageCache        ImageCache
 }
 
-func NewGrafana(url string, token string, timeout time.Duration, attrs ImageAttributes) Grafana {
-	return &grafana{
-		client: newClient(url, token, timeout),
-		attrs:  attrs,
+func NewGrafana(url string, token string, timeout time.Duration, attrs ImageAttributes, opts ...Option) Grafana {
+	g := &grafana{
+		client:            newClient(url, token, timeout),
+		attrs:             attrs,
+		renderConcurrency: defaultRenderConcurrency,
+		renderBucket:      defaultRenderBucket,
 	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	g.client.telemetry = newTelemetry(g.telemetry)
+
+	return g
 }
 
 func (g *grafana) Panels(ctx context.Context, dashboardUID string, filterPanelNames ...string) ([]Panel, error) {
-	var result = make(panelMap)
-	dashboard, err := g.client.getDashboard(ctx, dashboardUID)
+	return g.SelectPanels(ctx, dashboardUID, Selector{Titles: filterPanelNames})
+}
 
+// SelectPanels returns the panels of the dashboard matching the selector. Only the selected panels are queried.
+// With WithPartialResults, panels that fail are returned with their Error set along with a *PanelsError.
+func (g *grafana) SelectPanels(ctx context.Context, dashboardUID string, selector Selector) ([]Panel, error) {
+	matcher, err := selector.compile()
 	if err != nil {
-		return nil, fmt.Errorf("error getting dashboard response: %w", err)
+		return nil, fmt.Errorf("invalid panel selector: %w", err)
 	}
 
-	alertStates, err := g.client.alertStates(ctx, dashboard.ID)
+	ctx = withAttributes(ctx, dashboardUIDKey.String(dashboardUID))
+
+	// library panels are resolved below, only those that may be selected
+	response, _, err := g.client.getDashboardResponse(ctx, dashboardUID)
 	if err != nil {
-		return nil, fmt.Errorf("error getting alert response: %w", err)
+		return nil, fmt.Errorf("error getting dashboard response: %w", err)
 	}
 
-	panels := make([]panelData, 0)
-	if len(filterPanelNames) > 0 {
-		panelNamesMap := make(map[string]bool)
-		for _, pn := range filterPanelNames {
-			panelNamesMap[pn] = true
-		}
-		for _, panel := range dashboard.Panels {
-			if _, ok := panelNamesMap[panel.Title]; ok {
-				panels = append(panels, panel)
-			}
+	dashboard := response.Data()
+
+	// in best-effort mode panels are returned without alert states, those with an alert get the error
+	alertStates, alertsErr := g.client.alertStates(ctx, dashboard.ID)
+	if alertsErr != nil {
+		if !g.partialResults {
+			return nil, fmt.Errorf("error getting alert response: %w", alertsErr)
 		}
-	} else {
-		panels = dashboard.Panels
+
+		alertsErr = fmt.Errorf("error getting alert states: %w", alertsErr)
 	}
 
-	for _, p := range panels {
-		currentValues, err := g.client.currentValues(ctx, p.Exprs)
-		if err != nil {
-			return nil, fmt.Errorf("error getting current values response: %w", err)
+	result := make([]Panel, 0, len(dashboard.Panels))
+
+	var panelErrs []*PanelError
+
+	for _, p := range dashboard.Panels {
+		if isLibraryReference(p) {
+			if !matcher.MatchReference(p) {
+				continue
+			}
+
+			resolved, err := g.client.resolveLibraryPanel(ctx, p)
+			if err != nil {
+				if !g.partialResults {
+					return nil, err
+				}
+
+				if p.Title == "" {
+					p.Title = p.LibraryPanel.Name
+				}
+
+				panelErrs = append(panelErrs, &PanelError{PanelID: p.ID, Title: p.Title, Err: err})
+				result = append(result, Panel{
+					ID:           p.ID,
+					DashboardUID: dashboardUID,
+					Title:        p.Title,
+					Row:          p.Row,
+					GridPos:      p.GridPos,
+					LibraryPanel: p.LibraryPanel,
+					Image:        g.getImageURL(ctx, dashboardUID, p.ID),
+					Error:        err.Error(),
+				})
+
+				continue
+			}
+
+			p = resolved
 		}
 
-		result[p.ID] = Panel{
-			Title:         p.Title,
-			CurrentValues: currentValues,
-			Image:         g.getImageURL(dashboardUID, p.ID),
-			Alert:         p.Alert,
+		panel := Panel{
+			ID:           p.ID,
+			DashboardUID: dashboardUID,
+			Title:        p.Title,
+			Type:         p.Type,
+			Row:          p.Row,
+			GridPos:      p.GridPos,
+			LibraryPanel: p.LibraryPanel,
+			Image:        g.getImageURL(ctx, dashboardUID, p.ID),
+			Alert:        p.Alert,
+			Thresholds:   p.FieldConfig.Thresholds,
 		}
 
 		if as, ok := alertStates[p.ID]; ok {
-			panel := result[p.ID]
-
 			panel.Alert.State = as.State
 			panel.Alert.Name = as.Name
+		}
+
+		if !matcher.Match(panel) {
+			continue
+		}
+
+		panelCtx := withAttributes(ctx, panelIDKey.Int(p.ID))
+
+		currentValues, err := g.panelValues(panelCtx, p)
+		if err != nil {
+			if !g.partialResults {
+				return nil, fmt.Errorf("error getting current values response: %w", err)
+			}
+
+			var queryErrs []*PanelError
+
+			currentValues, queryErrs = g.partialPanelValues(panelCtx, p)
+			panel.Error = err.Error()
+
+			if len(queryErrs) == 0 {
+				queryErrs = []*PanelError{{PanelID: p.ID, Title: p.Title, Err: err}}
+			}
 
-			result[p.ID] = panel
+			panelErrs = append(panelErrs, queryErrs...)
 		}
+
+		if alertsErr != nil && panel.Alert.Name != "" {
+			if panel.Error == "" {
+				panel.Error = alertsErr.Error()
+			}
+
+			panelErrs = append(panelErrs, &PanelError{PanelID: p.ID, Title: p.Title, Err: alertsErr})
+		}
+
+		p.display(currentValues)
+		panel.CurrentValues = currentValues
+
+		result = append(result, panel)
 	}
 
-	return result.ToSlice(), nil
+	if len(panelErrs) > 0 {
+		return result, &PanelsError{DashboardUID: dashboardUID, Errors: panelErrs}
+	}
 
+	return result, nil
+}
+
+// partialPanelValues evaluates the queries of a failed panel one by one, so the queries that work keep
+// their values and the others get their error.
+func (g *grafana) partialPanelValues(ctx context.Context, p panelData) ([]CurrentValue, []*PanelError) {
+	values := make([]CurrentValue, 0, len(p.Exprs))
+
+	var errs []*PanelError
+
+	for _, e := range p.Exprs {
+		single := p
+		single.Exprs = []expr{e}
+
+		v, err := g.panelValues(ctx, single)
+		if err != nil {
+			errs = append(errs, &PanelError{PanelID: p.ID, Title: p.Title, Query: e.Query, Err: err})
+			values = append(values, CurrentValue{Query: e.Query, Error: err.Error()})
+
+			continue
+		}
+
+		values = append(values, v...)
+	}
+
+	return values, errs
+}
+
+// PanelByID returns the panel with the id, which unlike the title is unique within a dashboard.
+// In best-effort mode a failed panel is returned along with a *PanelsError.
+func (g *grafana) PanelByID(ctx context.Context, dashboardUID string, id int) (*Panel, error) {
+	panels, err := g.SelectPanels(ctx, dashboardUID, Selector{IDs: []int{id}})
+	if err != nil && !IsPartial(err) {
+		return nil, fmt.Errorf("failed to get grafana panels: %w", err)
+	}
+
+	if len(panels) == 0 {
+		return nil, fmt.Errorf("panel with id %d not found", id)
+	}
+
+	return &panels[0], err
+}
+
+// panelValues returns the values the panel displays: the latest ones, or a calculation or all values
+// over the panel time range for stats and gauges.
+func (g *grafana) panelValues(ctx context.Context, p panelData) ([]CurrentValue, error) {
+	if p.Reduce == nil {
+		return g.client.currentValues(ctx, p.Exprs, time.Time{})
+	}
+
+	from, to, err := p.TimeRange.Resolve(time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve time range of panel %s: %w", p.Title, err)
+	}
+
+	return g.client.reducedValues(ctx, p.Exprs, from, to, *p.Reduce)
 }
 
 func (g *grafana) GetPanelPicture(url string) ([]byte, error) {
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	return g.panelPicture(context.Background(), url)
+}
+
+func (g *grafana) panelPicture(ctx context.Context, url string) (_ []byte, err error) {
+	ctx, op := g.client.telemetry.start(ctx, operationImage, imageAttributes(url)...)
+	defer func() { op.end(err) }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create http request: %w", err)
 	}
 
 	req.Header.Add(authHeader, g.client.token)
-	resp, err := g.client.client.Do(req)
+
+	// the organization of the image is the one it was rendered for
+	if orgID := req.URL.Query().Get(orgIDParam); orgID != "" {
+		req.Header.Set(orgIDHeader, orgID)
+	}
+
+	resp, err := g.client.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to do http request: %w", err)
 	}
@@ -114,29 +300,61 @@ func (g *grafana) GetPanelPicture(url string) ([]byte, error) {
 
 func (g *grafana) GetGrafanaPanel(panelName string, dashboardID string) (*Panel, error) {
 	panels, err := g.Panels(context.Background(), dashboardID, panelName)
-	if err != nil {
+	if err != nil && !IsPartial(err) {
 		return nil, fmt.Errorf("failed to get grafana panels: %w", err)
 	}
-	if len(panels) == 1 {
-		return &panels[0], nil
+	switch len(panels) {
+	case 0:
+		return nil, fmt.Errorf("panel with name %s not found", panelName)
+	case 1:
+		return &panels[0], err
+	}
+
+	return nil, fmt.Errorf("%d panels are named %s, use PanelByID", len(panels), panelName)
+}
+
+// Query evaluates an instant query at the given time. A zero time means now.
+func (g *grafana) Query(ctx context.Context, query string, at time.Time) ([]LabelValue, error) {
+	values, err := g.client.query(ctx, query, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", query, err)
+	}
+
+	return values, nil
+}
+
+// QueryRange evaluates a range query. A non-positive step falls back to a default one.
+func (g *grafana) QueryRange(ctx context.Context, query string, from, to time.Time, step time.Duration) ([]Series, error) {
+	series, err := g.client.queryRange(ctx, query, from, to, step)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query range %s: %w", query, err)
 	}
 
-	return nil, fmt.Errorf("panel with name %s not found", panelName)
+	return series, nil
 }
 
-func (g *grafana) getImageURL(dashboardUID string, panelID int) string {
+func (g *grafana) getImageURL(ctx context.Context, dashboardUID string, panelID int) string {
 	to := time.Now()
-	from := to.Add(-12 * time.Hour)
-
-	return fmt.Sprintf(
-		imageURLFormat,
-		g.client.url,
-		dashboardUID,
-		from.UnixMilli(),
-		to.UnixMilli(),
-		panelID,
-		g.attrs.Width,
-		g.attrs.Height,
-		g.attrs.Timezone,
-	)
+
+	return g.imageURL(ctx, dashboardUID, panelID, g.attrs, to.Add(-defaultImageRange), to)
+}
+
+// imageAttributes describes the panel of a render URL built by getImageURL.
+func imageAttributes(imageURL string) []attribute.KeyValue {
+	u, err := url.Parse(imageURL)
+	if err != nil {
+		return nil
+	}
+
+	var attrs []attribute.KeyValue
+
+	if parts := strings.Split(strings.TrimPrefix(u.Path, "/"), "/"); len(parts) >= 3 && parts[0] == "render" {
+		attrs = append(attrs, dashboardUIDKey.String(parts[2]))
+	}
+
+	if panelID, err := strconv.Atoi(u.Query().Get("panelId")); err == nil {
+		attrs = append(attrs, panelIDKey.Int(panelID))
+	}
+
+	return attrs
 }
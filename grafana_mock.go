@@ -7,6 +7,7 @@ package grafana
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -34,6 +35,109 @@ func (m *MockGrafana) EXPECT() *MockGrafanaMockRecorder {
 	return m.recorder
 }
 
+// AlertRoutes mocks base method.
+func (m *MockGrafana) AlertRoutes(ctx context.Context, dashboardUID string) ([]AlertRoute, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AlertRoutes", ctx, dashboardUID)
+	ret0, _ := ret[0].([]AlertRoute)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AlertRoutes indicates an expected call of AlertRoutes.
+func (mr *MockGrafanaMockRecorder) AlertRoutes(ctx, dashboardUID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AlertRoutes", reflect.TypeOf((*MockGrafana)(nil).AlertRoutes), ctx, dashboardUID)
+}
+
+// Annotations mocks base method.
+func (m *MockGrafana) Annotations(ctx context.Context, q AnnotationQuery) ([]Annotation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Annotations", ctx, q)
+	ret0, _ := ret[0].([]Annotation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Annotations indicates an expected call of Annotations.
+func (mr *MockGrafanaMockRecorder) Annotations(ctx, q interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Annotations", reflect.TypeOf((*MockGrafana)(nil).Annotations), ctx, q)
+}
+
+// ContactPoints mocks base method.
+func (m *MockGrafana) ContactPoints(ctx context.Context) ([]ContactPoint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ContactPoints", ctx)
+	ret0, _ := ret[0].([]ContactPoint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ContactPoints indicates an expected call of ContactPoints.
+func (mr *MockGrafanaMockRecorder) ContactPoints(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContactPoints", reflect.TypeOf((*MockGrafana)(nil).ContactPoints), ctx)
+}
+
+// CreateAnnotation mocks base method.
+func (m *MockGrafana) CreateAnnotation(ctx context.Context, a Annotation) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAnnotation", ctx, a)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAnnotation indicates an expected call of CreateAnnotation.
+func (mr *MockGrafanaMockRecorder) CreateAnnotation(ctx, a interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAnnotation", reflect.TypeOf((*MockGrafana)(nil).CreateAnnotation), ctx, a)
+}
+
+// CreateSnapshot mocks base method.
+func (m *MockGrafana) CreateSnapshot(ctx context.Context, dashboardUID string, from, to time.Time, expires time.Duration) (*Snapshot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSnapshot", ctx, dashboardUID, from, to, expires)
+	ret0, _ := ret[0].(*Snapshot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSnapshot indicates an expected call of CreateSnapshot.
+func (mr *MockGrafanaMockRecorder) CreateSnapshot(ctx, dashboardUID, from, to, expires interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSnapshot", reflect.TypeOf((*MockGrafana)(nil).CreateSnapshot), ctx, dashboardUID, from, to, expires)
+}
+
+// DeleteAnnotation mocks base method.
+func (m *MockGrafana) DeleteAnnotation(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAnnotation", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAnnotation indicates an expected call of DeleteAnnotation.
+func (mr *MockGrafanaMockRecorder) DeleteAnnotation(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAnnotation", reflect.TypeOf((*MockGrafana)(nil).DeleteAnnotation), ctx, id)
+}
+
+// DeleteSnapshot mocks base method.
+func (m *MockGrafana) DeleteSnapshot(ctx context.Context, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSnapshot", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSnapshot indicates an expected call of DeleteSnapshot.
+func (mr *MockGrafanaMockRecorder) DeleteSnapshot(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSnapshot", reflect.TypeOf((*MockGrafana)(nil).DeleteSnapshot), ctx, key)
+}
+
 // GetGrafanaPanel mocks base method.
 func (m *MockGrafana) GetGrafanaPanel(panelName, dashboardID string) (*Panel, error) {
 	m.ctrl.T.Helper()
@@ -64,6 +168,66 @@ func (mr *MockGrafanaMockRecorder) GetPanelPicture(url interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPanelPicture", reflect.TypeOf((*MockGrafana)(nil).GetPanelPicture), url)
 }
 
+// ListSnapshots mocks base method.
+func (m *MockGrafana) ListSnapshots(ctx context.Context) ([]Snapshot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSnapshots", ctx)
+	ret0, _ := ret[0].([]Snapshot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSnapshots indicates an expected call of ListSnapshots.
+func (mr *MockGrafanaMockRecorder) ListSnapshots(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSnapshots", reflect.TypeOf((*MockGrafana)(nil).ListSnapshots), ctx)
+}
+
+// NotificationChannels mocks base method.
+func (m *MockGrafana) NotificationChannels(ctx context.Context) ([]NotificationChannel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NotificationChannels", ctx)
+	ret0, _ := ret[0].([]NotificationChannel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NotificationChannels indicates an expected call of NotificationChannels.
+func (mr *MockGrafanaMockRecorder) NotificationChannels(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NotificationChannels", reflect.TypeOf((*MockGrafana)(nil).NotificationChannels), ctx)
+}
+
+// Orgs mocks base method.
+func (m *MockGrafana) Orgs(ctx context.Context) ([]Org, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Orgs", ctx)
+	ret0, _ := ret[0].([]Org)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Orgs indicates an expected call of Orgs.
+func (mr *MockGrafanaMockRecorder) Orgs(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Orgs", reflect.TypeOf((*MockGrafana)(nil).Orgs), ctx)
+}
+
+// PanelByID mocks base method.
+func (m *MockGrafana) PanelByID(ctx context.Context, dashboardUID string, id int) (*Panel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PanelByID", ctx, dashboardUID, id)
+	ret0, _ := ret[0].(*Panel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PanelByID indicates an expected call of PanelByID.
+func (mr *MockGrafanaMockRecorder) PanelByID(ctx, dashboardUID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PanelByID", reflect.TypeOf((*MockGrafana)(nil).PanelByID), ctx, dashboardUID, id)
+}
+
 // Panels mocks base method.
 func (m *MockGrafana) Panels(ctx context.Context, dashboardUid string, filterPanelNames ...string) ([]Panel, error) {
 	m.ctrl.T.Helper()
@@ -83,3 +247,105 @@ func (mr *MockGrafanaMockRecorder) Panels(ctx, dashboardUid interface{}, filterP
 	varargs := append([]interface{}{ctx, dashboardUid}, filterPanelNames...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Panels", reflect.TypeOf((*MockGrafana)(nil).Panels), varargs...)
 }
+
+// Query mocks base method.
+func (m *MockGrafana) Query(ctx context.Context, query string, at time.Time) ([]LabelValue, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Query", ctx, query, at)
+	ret0, _ := ret[0].([]LabelValue)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Query indicates an expected call of Query.
+func (mr *MockGrafanaMockRecorder) Query(ctx, query, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Query", reflect.TypeOf((*MockGrafana)(nil).Query), ctx, query, at)
+}
+
+// QueryRange mocks base method.
+func (m *MockGrafana) QueryRange(ctx context.Context, query string, from, to time.Time, step time.Duration) ([]Series, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryRange", ctx, query, from, to, step)
+	ret0, _ := ret[0].([]Series)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryRange indicates an expected call of QueryRange.
+func (mr *MockGrafanaMockRecorder) QueryRange(ctx, query, from, to, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryRange", reflect.TypeOf((*MockGrafana)(nil).QueryRange), ctx, query, from, to, step)
+}
+
+// RenderPanels mocks base method.
+func (m *MockGrafana) RenderPanels(ctx context.Context, panels []Panel, attrs ImageAttributes, from, to time.Time) ([][]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenderPanels", ctx, panels, attrs, from, to)
+	ret0, _ := ret[0].([][]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RenderPanels indicates an expected call of RenderPanels.
+func (mr *MockGrafanaMockRecorder) RenderPanels(ctx, panels, attrs, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenderPanels", reflect.TypeOf((*MockGrafana)(nil).RenderPanels), ctx, panels, attrs, from, to)
+}
+
+// SelectPanels mocks base method.
+func (m *MockGrafana) SelectPanels(ctx context.Context, dashboardUID string, selector Selector) ([]Panel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectPanels", ctx, dashboardUID, selector)
+	ret0, _ := ret[0].([]Panel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectPanels indicates an expected call of SelectPanels.
+func (mr *MockGrafanaMockRecorder) SelectPanels(ctx, dashboardUID, selector interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectPanels", reflect.TypeOf((*MockGrafana)(nil).SelectPanels), ctx, dashboardUID, selector)
+}
+
+// TestContactPoint mocks base method.
+func (m *MockGrafana) TestContactPoint(ctx context.Context, point ContactPoint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TestContactPoint", ctx, point)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TestContactPoint indicates an expected call of TestContactPoint.
+func (mr *MockGrafanaMockRecorder) TestContactPoint(ctx, point interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TestContactPoint", reflect.TypeOf((*MockGrafana)(nil).TestContactPoint), ctx, point)
+}
+
+// TestNotificationChannel mocks base method.
+func (m *MockGrafana) TestNotificationChannel(ctx context.Context, channel NotificationChannel) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TestNotificationChannel", ctx, channel)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TestNotificationChannel indicates an expected call of TestNotificationChannel.
+func (mr *MockGrafanaMockRecorder) TestNotificationChannel(ctx, channel interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TestNotificationChannel", reflect.TypeOf((*MockGrafana)(nil).TestNotificationChannel), ctx, channel)
+}
+
+// UpdateAnnotation mocks base method.
+func (m *MockGrafana) UpdateAnnotation(ctx context.Context, a Annotation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAnnotation", ctx, a)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAnnotation indicates an expected call of UpdateAnnotation.
+func (mr *MockGrafanaMockRecorder) UpdateAnnotation(ctx, a interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAnnotation", reflect.TypeOf((*MockGrafana)(nil).UpdateAnnotation), ctx, a)
+}
@@ -1,10 +1,11 @@
 package grafana
 
 import (
+	"bytes"
 	"context"
-	"io/ioutil"
+	"errors"
 	"net/http"
-	"os"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -13,28 +14,23 @@ const (
 	timeout = 5 * time.Second
 )
 
-var token string
-var addr string
-var dashboardUID string
-
 func TestPanels(t *testing.T) {
-	if testing.Short() {
-		t.Skip("Skipping test in short mode")
+	_, inst := newFakeGrafanaClient(t)
+
+	panels, err := inst.Panels(context.Background(), fakeDashboardUID)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	dashboardUID = os.Getenv("GRAFANA_DASHBOARD_UID")
-	if dashboardUID == "" {
-		t.Fatal("Failed grafana test: no dashboard uid")
+	if len(panels) != 3 {
+		t.Fatalf("got %d panels, want 3", len(panels))
 	}
-	inst := NewGrafana(addr, token, timeout, ImageAttributes{
-		Height:   500,
-		Width:    1000,
-		Timezone: "Europe/Moscow",
-	})
 
-	panels, err := inst.Panels(context.Background(), dashboardUID)
-	if err != nil {
-		t.Fatal(err)
+	// panels keep the dashboard order
+	for i, title := range []string{"Update global index", "Config checksum", "Slashing: Jailed Validators"} {
+		if panels[i].Title != title {
+			t.Fatalf("got panel %q at %d, want %q", panels[i].Title, i, title)
+		}
 	}
 
 	for _, p := range panels {
@@ -46,76 +42,353 @@ func TestPanels(t *testing.T) {
 			t.Fatalf("current values is empty! title is %s", p.Title)
 		}
 
-		resp, err := http.Get(p.Image)
+		body, err := inst.GetPanelPicture(p.Image)
 		if err != nil {
 			t.Fatalf("failed to get image %s", err)
 		}
 
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			t.Fatalf("failed to read image body: %s", err)
+		if len(body) == 0 {
+			t.Fatal("image is empty")
 		}
 
-		if len(body) == 0 {
-			t.Fatalf("image is empty: %s", err)
+		if p.Title == "Update global index" {
+			colors := make(map[string]string)
+			formatted := make(map[string]string)
+
+			for _, cv := range p.CurrentValues {
+				for _, v := range cv.Values {
+					if v.Threshold == nil {
+						t.Fatalf("value %s is not classified", v.Label)
+					}
+
+					colors[v.Label] = v.Threshold.Color
+					formatted[v.Label] = v.FormattedValue
+				}
+			}
+
+			if formatted["gas used"] != "512.3 K" || formatted["gas wanted"] != "600.0 K" || formatted["uusd fee"] != "90.0 K" {
+				t.Fatalf("wrong formatted values: %v", formatted)
+			}
+
+			if colors["gas used"] != "green" || colors["gas wanted"] != "orange" || colors["uusd fee"] != "green" {
+				t.Fatalf("wrong threshold colors: %v", colors)
+			}
+		}
+
+		if p.Title == "Config checksum" {
+			want := []LabelValue{
+				{Label: "hub", Value: "3735928559", FormattedValue: "3735928559"},
+				{Label: "reward", Value: "405419896", FormattedValue: "405419896"},
+			}
+			if !reflect.DeepEqual(p.CurrentValues[0].Values, want) {
+				t.Fatalf("stat values are not reduced with max: %+v", p.CurrentValues[0].Values)
+			}
+		}
+
+		if p.Title != "Slashing: Jailed Validators" {
+			continue
+		}
+
+		if p.Row != "Slashing" {
+			t.Fatalf("panel of a collapsed row has row %q", p.Row)
+		}
+
+		if p.Alert.State != "alerting" || p.Alert.Name != "Jailed validators alert" {
+			t.Fatalf("alert state is not merged: %+v", p.Alert)
+		}
+
+		if len(p.Alert.Conditions) != 1 || p.Alert.Conditions[0].Type != "gt" {
+			t.Fatalf("wrong alert conditions: %+v", p.Alert.Conditions)
+		}
+
+		if p.CurrentValues[0].Values[0] != (LabelValue{Label: "jailed", Value: "2", FormattedValue: "2"}) {
+			t.Fatalf("wrong current value: %+v", p.CurrentValues[0].Values)
 		}
 	}
 }
 
 func TestPanelsFiltered(t *testing.T) {
-	if testing.Short() {
-		t.Skip("Skipping test in short mode")
-	}
-	inst := NewGrafana(addr, token, timeout, ImageAttributes{
-		Height:   500,
-		Width:    1000,
-		Timezone: "Europe/Moscow",
-	})
-	panels, err := inst.Panels(context.Background(), dashboardUID, "Slashing: Jailed Validators")
+	_, inst := newFakeGrafanaClient(t)
+
+	panels, err := inst.Panels(context.Background(), fakeDashboardUID, "Slashing: Jailed Validators")
 	if err != nil {
 		t.Fatal(err)
 	}
+
 	if len(panels) != 1 {
 		t.Fatal("List is not filtered")
 	}
 }
 
+func TestPanelsErrors(t *testing.T) {
+	tests := map[string]struct {
+		path    string
+		handler http.HandlerFunc
+	}{
+		"dashboard not found": {
+			path: dashboardPath + fakeDashboardUID,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+		},
+		"malformed dashboard": {
+			path: dashboardPath + fakeDashboardUID,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"dashboard":`))
+			},
+		},
+		"alerts server error": {
+			path: alertsPath,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+		},
+		"malformed alerts": {
+			path: alertsPath,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{}`))
+			},
+		},
+		"prometheus error": {
+			path: datasourcesPath + instantQueryPath,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				w.Write([]byte(`{"status":"error","errorType":"execution","error":"query timed out"}`))
+			},
+		},
+		"malformed prometheus response": {
+			path: datasourcesPath + instantQueryPath,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`<html>bad gateway</html>`))
+			},
+		},
+		"datasource proxy server error": {
+			path: datasourcesPath + instantQueryPath,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		f, inst := newFakeGrafanaClient(t)
+		f.handle(tt.path, tt.handler)
+
+		if _, err := inst.Panels(context.Background(), fakeDashboardUID); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestPanelsUnauthorized(t *testing.T) {
+	f := newFakeGrafana(t)
+	inst := NewGrafana(f.URL, "Bearer wrong", timeout, ImageAttributes{})
+
+	if _, err := inst.Panels(context.Background(), fakeDashboardUID); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
 func TestGetPanelPicture(t *testing.T) {
-	if testing.Short() {
-		t.Skip("Skipping test in short mode")
-	}
-	inst := NewGrafana(addr, token, timeout, ImageAttributes{
-		Height:   500,
-		Width:    1000,
-		Timezone: "Europe/Moscow",
-	})
-
-	panels, _ := inst.Panels(context.Background(), dashboardUID)
-	image := panels[0].Image
-	imageBody, err := inst.GetPanelPicture(image)
+	f, inst := newFakeGrafanaClient(t)
+
+	panels, err := inst.Panels(context.Background(), fakeDashboardUID)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(imageBody) == 0 {
-		t.Fatal("image is empty")
+
+	imageBody, err := inst.GetPanelPicture(panels[0].Image)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(imageBody, readFixture(t, "panel.png")) {
+		t.Fatal("wrong image")
+	}
+
+	if _, err = inst.GetPanelPicture(f.URL + "/render/d-solo/unknown/lido-monitors"); err == nil {
+		t.Fatal("expected error for missing image")
 	}
 }
 
 func TestGetGrafanaPanel(t *testing.T) {
-	if testing.Short() {
-		t.Skip("Skipping test in short mode")
-	}
-	inst := NewGrafana(addr, token, timeout, ImageAttributes{
-		Height:   500,
-		Width:    1000,
-		Timezone: "Europe/Moscow",
-	})
+	_, inst := newFakeGrafanaClient(t)
 
-	panel, err := inst.GetGrafanaPanel("Slashing: Jailed Validators", dashboardUID)
+	panel, err := inst.GetGrafanaPanel("Slashing: Jailed Validators", fakeDashboardUID)
 	if err != nil {
 		t.Fatal(err)
 	}
+
 	if panel.Title != "Slashing: Jailed Validators" {
 		t.Fatal("Panel not found")
 	}
+
+	if _, err = inst.GetGrafanaPanel("Unknown", fakeDashboardUID); err == nil {
+		t.Fatal("expected error for unknown panel")
+	}
+}
+
+func TestSelectPanels(t *testing.T) {
+	_, inst := newFakeGrafanaClient(t)
+
+	tests := map[string]struct {
+		selector Selector
+		ids      []int
+	}{
+		"all":         {Selector{}, []int{2, 3, 4}},
+		"row":         {Selector{Rows: []string{"Oracle"}}, []int{2, 3}},
+		"type":        {Selector{Types: []string{"stat"}}, []int{3}},
+		"glob":        {Selector{TitleGlob: "Slashing:*"}, []int{4}},
+		"alert state": {Selector{AlertStates: []string{"alerting"}}, []int{4}},
+		"none":        {Selector{TitleRegexp: "^Unknown$"}, []int{}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			panels, err := inst.SelectPanels(context.Background(), fakeDashboardUID, tt.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ids := make([]int, 0, len(panels))
+			for _, p := range panels {
+				ids = append(ids, p.ID)
+			}
+
+			if !reflect.DeepEqual(ids, tt.ids) {
+				t.Fatalf("wrong panels: %v", ids)
+			}
+		})
+	}
+
+	if _, err := inst.SelectPanels(context.Background(), fakeDashboardUID, Selector{TitleRegexp: "("}); err == nil {
+		t.Fatal("expected error for invalid selector")
+	}
+}
+
+func TestPanelByID(t *testing.T) {
+	_, inst := newFakeGrafanaClient(t)
+
+	panel, err := inst.PanelByID(context.Background(), fakeDashboardUID, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if panel.Title != "Slashing: Jailed Validators" || panel.Type != "graph" {
+		t.Fatalf("wrong panel: %+v", panel)
+	}
+
+	if panel.GridPos == nil || *panel.GridPos != (GridPos{X: 0, Y: 10, W: 24, H: 8}) {
+		t.Fatalf("wrong grid position: %+v", panel.GridPos)
+	}
+
+	if _, err = inst.PanelByID(context.Background(), fakeDashboardUID, 42); err == nil {
+		t.Fatal("expected error for unknown panel")
+	}
+}
+
+func TestPanelsPartialResults(t *testing.T) {
+	const failing = "update_global_index_gas_wanted{}"
+
+	newFake := func(opts ...Option) Grafana {
+		f, inst := newFakeGrafanaClient(t, opts...)
+		f.handle(datasourcesPath+instantQueryPath, func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query().Get("query")
+			if query == failing {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				w.Write([]byte(`{"status":"error","errorType":"execution","error":"query timed out"}`))
+
+				return
+			}
+
+			w.Write(f.queries[query])
+		})
+
+		return inst
+	}
+
+	if _, err := newFake().Panels(context.Background(), fakeDashboardUID); err == nil || IsPartial(err) {
+		t.Fatalf("strict mode must fail: %v", err)
+	}
+
+	panels, err := newFake(WithPartialResults(true)).Panels(context.Background(), fakeDashboardUID)
+
+	var partial *PanelsError
+	if !errors.As(err, &partial) {
+		t.Fatalf("expected partial results error, got %v", err)
+	}
+
+	if len(partial.Errors) != 1 || partial.Errors[0].PanelID != 2 || partial.Errors[0].Query != failing {
+		t.Fatalf("wrong panel errors: %v", partial)
+	}
+
+	if len(panels) != 3 {
+		t.Fatalf("got %d panels, want every panel", len(panels))
+	}
+
+	updates := panels[0]
+	if updates.Error == "" {
+		t.Fatal("failed panel has no error")
+	}
+
+	if len(updates.CurrentValues) != 3 {
+		t.Fatalf("wrong current values: %+v", updates.CurrentValues)
+	}
+
+	if cv := updates.CurrentValues[1]; cv.Query != failing || cv.Error == "" || len(cv.Values) != 0 {
+		t.Fatalf("failed query has no error: %+v", cv)
+	}
+
+	if cv := updates.CurrentValues[0]; cv.Error != "" || len(cv.Values) != 1 {
+		t.Fatalf("working query lost its values: %+v", cv)
+	}
+
+	for _, p := range panels[1:] {
+		if p.Error != "" {
+			t.Fatalf("panel %d must not fail: %s", p.ID, p.Error)
+		}
+	}
+
+	panel, err := newFake(WithPartialResults(true)).PanelByID(context.Background(), fakeDashboardUID, 2)
+	if !IsPartial(err) || panel == nil || panel.Error == "" {
+		t.Fatalf("expected failed panel with partial error, got %+v, %v", panel, err)
+	}
+}
+
+func TestPanelsPartialAlertStates(t *testing.T) {
+	newFake := func(opts ...Option) Grafana {
+		f, inst := newFakeGrafanaClient(t, opts...)
+		f.handle(alertsPath, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+
+		return inst
+	}
+
+	if _, err := newFake().Panels(context.Background(), fakeDashboardUID); err == nil || IsPartial(err) {
+		t.Fatalf("strict mode must fail: %v", err)
+	}
+
+	panels, err := newFake(WithPartialResults(true)).Panels(context.Background(), fakeDashboardUID)
+	if !IsPartial(err) {
+		t.Fatalf("expected partial results error, got %v", err)
+	}
+
+	if len(panels) != 3 {
+		t.Fatalf("got %d panels, want every panel", len(panels))
+	}
+
+	for _, p := range panels {
+		if failed := p.Error != ""; failed != (p.Alert.Name != "") {
+			t.Fatalf("only panels with an alert must fail: %+v", p)
+		}
+
+		if len(p.CurrentValues) == 0 {
+			t.Fatalf("panel %d has no values", p.ID)
+		}
+	}
+
+	if partial := err.(*PanelsError); partial.DashboardUID != fakeDashboardUID || len(partial.Errors) != 1 {
+		t.Fatalf("wrong panel errors: %v", partial)
+	}
 }
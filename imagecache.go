@@ -0,0 +1,156 @@
+package grafana
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const imageCacheExt = ".png"
+
+// ImageCache keeps rendered panel images by their render URL.
+type ImageCache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, image []byte) error
+}
+
+// memoryImageCache keeps images in memory for a TTL.
+type memoryImageCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]imageCacheEntry
+}
+
+type imageCacheEntry struct {
+	image   []byte
+	expires time.Time
+}
+
+// NewMemoryImageCache keeps images in memory for the TTL. Expired images are dropped on Set.
+func NewMemoryImageCache(ttl time.Duration) ImageCache {
+	return &memoryImageCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]imageCacheEntry),
+	}
+}
+
+func (c *memoryImageCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok || !c.now().Before(e.expires) {
+		return nil, false
+	}
+
+	return e.image, true
+}
+
+func (c *memoryImageCache) Set(key string, image []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	c.entries[key] = imageCacheEntry{image: image, expires: now.Add(c.ttl)}
+
+	return nil
+}
+
+// diskImageCache keeps images as files named by the hash of the key; the file age is the entry age.
+type diskImageCache struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+}
+
+// NewDiskImageCache keeps images as files in dir for the TTL, so they survive restarts and can be
+// shared by processes. Expired files are removed on Set.
+func NewDiskImageCache(dir string, ttl time.Duration) (ImageCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create image cache directory: %w", err)
+	}
+
+	return &diskImageCache{dir: dir, ttl: ttl, now: time.Now}, nil
+}
+
+func (c *diskImageCache) Get(key string) ([]byte, bool) {
+	name := c.path(key)
+
+	info, err := os.Stat(name)
+	if err != nil || c.expired(info) {
+		return nil, false
+	}
+
+	image, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, false
+	}
+
+	return image, true
+}
+
+func (c *diskImageCache) Set(key string, image []byte) error {
+	c.evictExpired()
+
+	// write to a temporary file first so concurrent readers never see a partial image
+	tmp, err := ioutil.TempFile(c.dir, "render-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create image cache file: %w", err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(image); err != nil {
+		tmp.Close()
+
+		return fmt.Errorf("failed to write image cache file: %w", err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write image cache file: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), c.path(key)); err != nil {
+		return fmt.Errorf("failed to store image cache file: %w", err)
+	}
+
+	return nil
+}
+
+func (c *diskImageCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+imageCacheExt)
+}
+
+func (c *diskImageCache) expired(info os.FileInfo) bool {
+	return !c.now().Before(info.ModTime().Add(c.ttl))
+}
+
+func (c *diskImageCache) evictExpired() {
+	names, err := filepath.Glob(filepath.Join(c.dir, "*"+imageCacheExt))
+	if err != nil {
+		return
+	}
+
+	for _, name := range names {
+		if info, err := os.Stat(name); err == nil && c.expired(info) {
+			os.Remove(name)
+		}
+	}
+}
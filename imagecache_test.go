@@ -0,0 +1,80 @@
+package grafana
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMemoryImageCache(t *testing.T) {
+	now := time.Date(2022, 4, 15, 10, 0, 0, 0, time.UTC)
+
+	c := NewMemoryImageCache(time.Minute).(*memoryImageCache)
+	c.now = func() time.Time { return now }
+
+	if err := c.Set("a", []byte("png")); err != nil {
+		t.Fatal(err)
+	}
+
+	if image, ok := c.Get("a"); !ok || string(image) != "png" {
+		t.Fatal("image is not cached")
+	}
+
+	now = now.Add(time.Minute)
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("expired image is served")
+	}
+
+	c.Set("b", []byte("png"))
+
+	if len(c.entries) != 1 {
+		t.Fatalf("expired images are not evicted: %d entries", len(c.entries))
+	}
+}
+
+func TestDiskImageCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "images")
+	now := time.Now()
+
+	cache, err := NewDiskImageCache(dir, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := cache.(*diskImageCache)
+	c.now = func() time.Time { return now }
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("missing image is served")
+	}
+
+	if err = c.Set("a", []byte("png")); err != nil {
+		t.Fatal(err)
+	}
+
+	if image, ok := c.Get("a"); !ok || !bytes.Equal(image, []byte("png")) {
+		t.Fatal("image is not cached")
+	}
+
+	now = now.Add(2 * time.Minute)
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("expired image is served")
+	}
+
+	if err = c.Set("b", []byte("png")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = os.Stat(c.path("a")); !os.IsNotExist(err) {
+		t.Fatal("expired image is not evicted")
+	}
+
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("got %d files, want 1", len(files))
+	}
+}
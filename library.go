@@ -0,0 +1,211 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const libraryElementsPath = "/api/library-elements/"
+
+type libraryElementDTO struct {
+	Result struct {
+		UID   string          `json:"uid"`
+		Name  string          `json:"name"`
+		Model json.RawMessage `json:"model"`
+	} `json:"result"`
+}
+
+// libraryPanelTTL is how long library panel models are cached, so changes to a library panel reach
+// long-lived clients.
+const libraryPanelTTL = 5 * time.Minute
+
+type libraryPanelEntry struct {
+	model   json.RawMessage
+	expires time.Time
+}
+
+// libraryPanelCall is a fetch of a library panel model that concurrent requests for it wait for.
+type libraryPanelCall struct {
+	done  chan struct{}
+	model json.RawMessage
+	err   error
+}
+
+// isLibraryReference reports whether a panel of the dashboard model refers to a library panel.
+func isLibraryReference(p panelData) bool {
+	return p.LibraryPanel != nil && p.LibraryPanel.UID != ""
+}
+
+// resolveLibraryPanels replaces library panel references with the panels of their library models.
+func (c *client) resolveLibraryPanels(ctx context.Context, panels []panelData) error {
+	for i, p := range panels {
+		if !isLibraryReference(p) {
+			continue
+		}
+
+		resolved, err := c.resolveLibraryPanel(ctx, p)
+		if err != nil {
+			return err
+		}
+
+		panels[i] = resolved
+	}
+
+	return nil
+}
+
+// resolveLibraryPanel returns the panel of the library model a reference points to. The dashboard
+// keeps its panel id, position, row and time range and the library panel identity.
+func (c *client) resolveLibraryPanel(ctx context.Context, ref panelData) (panelData, error) {
+	model, err := c.libraryPanelModel(ctx, ref.LibraryPanel.UID)
+	if err != nil {
+		return panelData{}, fmt.Errorf("failed to get library panel %s: %w", ref.LibraryPanel.UID, err)
+	}
+
+	var resolved panel
+	if err = json.Unmarshal(model, &resolved); err != nil {
+		return panelData{}, fmt.Errorf("failed to unmarshal library panel %s model: %w", ref.LibraryPanel.UID, err)
+	}
+
+	library := *ref.LibraryPanel
+
+	if resolved.Title == "" {
+		resolved.Title = ref.Title
+	}
+
+	if library.Name == "" {
+		library.Name = resolved.Title
+	}
+
+	resolved.ID = ref.ID
+	resolved.GridPos = ref.GridPos
+	resolved.LibraryPanel = &library
+
+	data := resolved.Data(ref.Row)
+	data.TimeRange.From = ref.TimeRange.From
+	data.TimeRange.To = ref.TimeRange.To
+
+	return data, nil
+}
+
+// libraryPanelModel returns the panel model of a library element. Models are cached per organization
+// for libraryPanelTTL, and concurrent requests for a model share a single fetch that none of them can
+// cancel for the others.
+func (c *client) libraryPanelModel(ctx context.Context, uid string) (json.RawMessage, error) {
+	key := fmt.Sprintf("%d/%s", c.org(ctx), uid)
+
+	c.libraryPanelsMu.Lock()
+
+	if e, ok := c.libraryPanels[key]; ok && time.Now().Before(e.expires) {
+		c.libraryPanelsMu.Unlock()
+
+		return e.model, nil
+	}
+
+	call, ok := c.libraryPanelCalls[key]
+	if !ok {
+		if c.libraryPanelCalls == nil {
+			c.libraryPanels = make(map[string]libraryPanelEntry)
+			c.libraryPanelCalls = make(map[string]*libraryPanelCall)
+		}
+
+		call = &libraryPanelCall{done: make(chan struct{})}
+		c.libraryPanelCalls[key] = call
+
+		go c.fetchLibraryPanelModel(detachedContext{ctx}, key, uid, call)
+	}
+
+	c.libraryPanelsMu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.model, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetchLibraryPanelModel gets the model for every caller waiting for the call and caches it.
+func (c *client) fetchLibraryPanelModel(ctx context.Context, key, uid string, call *libraryPanelCall) {
+	call.model, call.err = c.getLibraryElement(ctx, uid)
+
+	c.libraryPanelsMu.Lock()
+	delete(c.libraryPanelCalls, key)
+
+	if call.err == nil {
+		now := time.Now()
+
+		for k, e := range c.libraryPanels {
+			if !now.Before(e.expires) {
+				delete(c.libraryPanels, k)
+			}
+		}
+
+		c.libraryPanels[key] = libraryPanelEntry{model: call.model, expires: now.Add(libraryPanelTTL)}
+	}
+
+	c.libraryPanelsMu.Unlock()
+	close(call.done)
+}
+
+// detachedContext keeps the values of a context, such as the organization and the trace, but not its
+// cancellation: a fetch shared by several callers must not fail for all of them when the one that
+// started it gives up. The request timeout of the client still bounds the fetch.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (detachedContext) Done() <-chan struct{} { return nil }
+
+func (detachedContext) Err() error { return nil }
+
+func (c *client) getLibraryElement(ctx context.Context, uid string) (_ json.RawMessage, err error) {
+	ctx, op := c.telemetry.start(ctx, operationLibraryPanel, libraryPanelUIDKey.String(uid))
+	defer func() { op.end(err) }()
+
+	var element libraryElementDTO
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		fmt.Sprintf("%s%s%s", c.url, libraryElementsPath, url.PathEscape(uid)),
+		nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create NewRequestWithContext: %w", err)
+	}
+
+	req.Header.Add(authHeader, c.token)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to do request: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed: status code is %d", resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read library element response body: %w", err)
+	}
+
+	if err = json.Unmarshal(body, &element); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal library element response: %w", err)
+	}
+
+	if len(element.Result.Model) == 0 {
+		return nil, fmt.Errorf("library element %s has no model", uid)
+	}
+
+	return element.Result.Model, nil
+}
@@ -0,0 +1,194 @@
+package grafana
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLibraryPanels(t *testing.T) {
+	f, inst := newFakeGrafanaClient(t)
+
+	f.handle(dashboardPath+fakeDashboardUID, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"dashboard":{"id":1,"uid":"monitors","panels":[
+			{"id":10,"libraryPanel":{"uid":"jailed-lib","name":"Jailed validators"}},
+			{"id":11,"type":"row","title":"Library","collapsed":true,"panels":[
+				{"id":12,"title":"Jailed copy","libraryPanel":{"uid":"jailed-lib"}}]}]}}`))
+	})
+
+	var fetched int
+
+	f.handle(libraryElementsPath+"jailed-lib", func(w http.ResponseWriter, r *http.Request) {
+		fetched++
+		w.Write(readFixture(t, "library_panel.json"))
+	})
+
+	for i := 0; i < 2; i++ {
+		panels, err := inst.Panels(context.Background(), fakeDashboardUID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(panels) != 2 {
+			t.Fatalf("got %d panels, want 2", len(panels))
+		}
+
+		for _, p := range panels {
+			if p.Title != "Slashing: Jailed Validators" {
+				t.Fatalf("library model title is not used: %q", p.Title)
+			}
+
+			if p.LibraryPanel == nil || p.LibraryPanel.UID != "jailed-lib" || p.LibraryPanel.Name == "" {
+				t.Fatalf("library panel is not recorded: %+v", p.LibraryPanel)
+			}
+
+			if len(p.CurrentValues) != 1 || p.CurrentValues[0].Values[0].Value != "2" {
+				t.Fatalf("library panel targets are not queried: %+v", p.CurrentValues)
+			}
+		}
+
+		if panels[0].ID != 10 || panels[1].ID != 12 || panels[1].Row != "Library" {
+			t.Fatalf("dashboard ids and rows are not kept: %+v", panels)
+		}
+	}
+
+	if fetched != 1 {
+		t.Fatalf("library panel fetched %d times, want 1", fetched)
+	}
+}
+
+func TestLibraryPanelNotFound(t *testing.T) {
+	f, inst := newFakeGrafanaClient(t)
+
+	f.handle(dashboardPath+fakeDashboardUID, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"dashboard":{"id":1,"panels":[{"id":10,"libraryPanel":{"uid":"missing"}}]}}`))
+	})
+
+	if _, err := inst.Panels(context.Background(), fakeDashboardUID); err == nil {
+		t.Fatal("expected error for a missing library panel")
+	}
+}
+
+func TestLibraryPanelsOfSelectedPanels(t *testing.T) {
+	f, inst := newFakeGrafanaClient(t, WithPartialResults(true))
+
+	f.handle(dashboardPath+fakeDashboardUID, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"dashboard":{"id":1,"uid":"monitors","panels":[
+			{"id":10,"libraryPanel":{"uid":"jailed-lib","name":"Jailed validators"}},
+			{"id":11,"libraryPanel":{"uid":"missing","name":"Removed panel"}}]}}`))
+	})
+
+	fetched := make(map[string]int)
+
+	f.handle(libraryElementsPath+"jailed-lib", func(w http.ResponseWriter, r *http.Request) {
+		fetched["jailed-lib"]++
+		w.Write(readFixture(t, "library_panel.json"))
+	})
+
+	f.handle(libraryElementsPath+"missing", func(w http.ResponseWriter, r *http.Request) {
+		fetched["missing"]++
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	p, err := inst.PanelByID(context.Background(), fakeDashboardUID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Title != "Slashing: Jailed Validators" || fetched["missing"] != 0 {
+		t.Fatalf("library panels of other panels are fetched: %v", fetched)
+	}
+
+	// a library panel that can't be fetched fails only its panel
+	panels, err := inst.Panels(context.Background(), fakeDashboardUID)
+	if !IsPartial(err) {
+		t.Fatalf("expected partial results, got %v", err)
+	}
+
+	if len(panels) != 2 || panels[0].Error != "" || len(panels[0].CurrentValues) != 1 {
+		t.Fatalf("working library panel is not returned: %+v", panels)
+	}
+
+	if panels[1].ID != 11 || panels[1].Title != "Removed panel" || panels[1].Error == "" {
+		t.Fatalf("failed library panel has no error: %+v", panels[1])
+	}
+}
+
+func TestLibraryPanelCacheExpires(t *testing.T) {
+	f, inst := newFakeGrafanaClient(t)
+
+	f.handle(dashboardPath+fakeDashboardUID, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"dashboard":{"id":1,"uid":"monitors","panels":[{"id":10,"libraryPanel":{"uid":"jailed-lib"}}]}}`))
+	})
+
+	var fetched int
+
+	f.handle(libraryElementsPath+"jailed-lib", func(w http.ResponseWriter, r *http.Request) {
+		fetched++
+		w.Write(readFixture(t, "library_panel.json"))
+	})
+
+	c := inst.(*grafana).client
+
+	for i := 0; i < 2; i++ {
+		if _, err := inst.Panels(context.Background(), fakeDashboardUID); err != nil {
+			t.Fatal(err)
+		}
+
+		c.libraryPanelsMu.Lock()
+		for key, e := range c.libraryPanels {
+			e.expires = time.Now()
+			c.libraryPanels[key] = e
+		}
+		c.libraryPanelsMu.Unlock()
+	}
+
+	if fetched != 2 {
+		t.Fatalf("expired library panel fetched %d times, want 2", fetched)
+	}
+}
+
+func TestLibraryPanelFetchOutlivesFirstCaller(t *testing.T) {
+	f, inst := newFakeGrafanaClient(t)
+
+	started, release := make(chan struct{}), make(chan struct{})
+
+	f.handle(libraryElementsPath+"jailed-lib", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write(readFixture(t, "library_panel.json"))
+	})
+
+	c := inst.(*grafana).client
+
+	first, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+
+	go func() {
+		_, err := c.libraryPanelModel(first, "jailed-lib")
+		firstErr <- err
+	}()
+
+	<-started
+
+	second := make(chan error)
+
+	go func() {
+		_, err := c.libraryPanelModel(context.Background(), "jailed-lib")
+		second <- err
+	}()
+
+	cancel()
+
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled caller got %v", err)
+	}
+
+	close(release)
+
+	if err := <-second; err != nil {
+		t.Fatalf("waiting caller failed with the first one: %v", err)
+	}
+}
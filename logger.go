@@ -0,0 +1,163 @@
+package grafana
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// LogLevel is the severity of a log record.
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = iota - 1
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelDebug:
+		return "DEBUG"
+	case LogLevelInfo:
+		return "INFO"
+	case LogLevelWarn:
+		return "WARN"
+	case LogLevelError:
+		return "ERROR"
+	}
+
+	return "UNKNOWN"
+}
+
+// Logger receives structured records of the client: every request with its status code and latency,
+// and retries of failed queries. keysAndValues are alternating string keys and values.
+type Logger interface {
+	Log(ctx context.Context, level LogLevel, msg string, keysAndValues ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Log(context.Context, LogLevel, string, ...interface{}) {}
+
+const (
+	// maxLoggedBody is how much of a response body is dumped in debug mode
+	maxLoggedBody = 2048
+	redacted      = "xxxxx"
+)
+
+// secretParams are query parameters that never reach the log
+var secretParams = map[string]bool{
+	"token":        true,
+	"api_key":      true,
+	"apikey":       true,
+	"access_token": true,
+	"auth":         true,
+	"password":     true,
+}
+
+// logResponse logs a finished request. In debug mode the redacted URL and the beginning of the
+// response body are logged as well, the body is buffered so that it can still be read by the caller.
+func (c *client) logResponse(req *http.Request, resp *http.Response, err error, latency time.Duration) {
+	ctx := req.Context()
+
+	if err != nil {
+		c.logger.Log(ctx, LogLevelError, "grafana request failed",
+			"method", req.Method,
+			"path", req.URL.Path,
+			"latency", latency,
+			"error", err)
+
+		return
+	}
+
+	level := LogLevelInfo
+	if resp.StatusCode >= http.StatusBadRequest {
+		level = LogLevelWarn
+	}
+
+	c.logger.Log(ctx, level, "grafana request",
+		"method", req.Method,
+		"path", req.URL.Path,
+		"status", resp.StatusCode,
+		"latency", latency)
+
+	if !c.debug {
+		return
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if err != nil {
+		// the caller reads what was read and then the error, not a body that looks complete
+		resp.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(body), errReader{err: err}))
+
+		c.logger.Log(ctx, LogLevelDebug, "grafana response",
+			"url", redactURL(req.URL),
+			"status", resp.StatusCode,
+			"error", err)
+
+		return
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	c.logger.Log(ctx, LogLevelDebug, "grafana response",
+		"url", redactURL(req.URL),
+		"status", resp.StatusCode,
+		"body", loggedBody(resp.Header.Get(contentTypeHeader), body))
+}
+
+// errReader fails every read with err.
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// redactURL hides the basic auth password of direct Prometheus URLs and secret query parameters.
+func redactURL(u *url.URL) string {
+	redactedURL := *u
+
+	q := redactedURL.Query()
+	for param := range q {
+		if secretParams[strings.ToLower(param)] {
+			q.Set(param, redacted)
+		}
+	}
+
+	redactedURL.RawQuery = q.Encode()
+
+	return redactedURL.Redacted()
+}
+
+func loggedBody(contentType string, body []byte) string {
+	if strings.HasPrefix(contentType, "image/") || !utf8.Valid(body) {
+		if contentType == "" {
+			return "<binary body>"
+		}
+
+		return "<" + contentType + " binary body>"
+	}
+
+	if len(body) <= maxLoggedBody {
+		return string(body)
+	}
+
+	cut := maxLoggedBody
+	for cut > 0 && !utf8.RuneStart(body[cut]) {
+		cut--
+	}
+
+	return string(body[:cut]) + "…"
+}
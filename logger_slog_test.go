@@ -0,0 +1,24 @@
+//go:build go1.21
+
+package grafana
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var b bytes.Buffer
+
+	logger := NewSlogLogger(slog.New(slog.NewTextHandler(&b, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	logger.Log(context.Background(), LogLevelInfo, "hidden")
+	logger.Log(context.Background(), LogLevelWarn, "grafana request", "status", 403)
+
+	if out := b.String(); strings.Contains(out, "hidden") || !strings.Contains(out, `level=WARN msg="grafana request" status=403`) {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
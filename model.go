@@ -1,16 +1,43 @@
 package grafana
 
+import "time"
+
 type Panel struct {
+	ID            int            `json:"id"`
+	DashboardUID  string         `json:"dashboard_uid,omitempty"`
 	Title         string         `json:"title"`
+	Type          string         `json:"type,omitempty"`
+	Row           string         `json:"row,omitempty"`
+	GridPos       *GridPos       `json:"grid_pos,omitempty"`
 	Image         string         `json:"image"`
 	Alert         Alert          `json:"alert"`
+	LibraryPanel  *LibraryPanel  `json:"library_panel,omitempty"`
+	Thresholds    Thresholds     `json:"thresholds"`
 	CurrentValues []CurrentValue `json:"current_value"`
+	// Error is why some values of the panel are missing, set in best-effort mode only
+	Error string `json:"error,omitempty"`
+}
+
+// GridPos is where a panel is on the dashboard grid: 24 columns wide, rows of 30 pixels.
+type GridPos struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// LibraryPanel identifies the library element a panel is an instance of.
+type LibraryPanel struct {
+	UID  string `json:"uid"`
+	Name string `json:"name"`
 }
 
 type Alert struct {
 	Name       string      `json:"name"`
 	State      string      `json:"state"`
 	Conditions []Condition `json:"conditions"`
+	// Notifications are the legacy channels the alert notifies besides the default ones
+	Notifications []NotificationRef `json:"notifications,omitempty"`
 }
 
 type Condition struct {
@@ -18,26 +45,156 @@ type Condition struct {
 	Values []float64 `json:"values"`
 }
 
+// Thresholds are the levels of fieldConfig.defaults.thresholds used by stat, gauge and timeseries panels.
+type Thresholds struct {
+	Mode  ThresholdsMode  `json:"mode,omitempty"`
+	Steps []ThresholdStep `json:"steps,omitempty"`
+}
+
+type ThresholdsMode string
+
+const (
+	ThresholdsModeAbsolute ThresholdsMode = "absolute"
+	// ThresholdsModePercentage steps are percents between the min and max of the field
+	ThresholdsModePercentage ThresholdsMode = "percentage"
+)
+
+// ThresholdStep starts at Value. The first step has no value and covers everything below the second one.
+type ThresholdStep struct {
+	Value *float64 `json:"value"`
+	Color string   `json:"color"`
+}
+
 type CurrentValue struct {
 	Query  string       `json:"query"`
 	Values []LabelValue `json:"values"`
+	// Error is why the query has no values, set in best-effort mode only
+	Error string `json:"error,omitempty"`
 }
 
 type LabelValue struct {
 	Label string `json:"label"`
 	Value string `json:"value"`
+	// Threshold is the step the value falls into, nil for panels without thresholds or values that are not numbers
+	Threshold *ThresholdStep `json:"threshold,omitempty"`
+	// FormattedValue is the value as Grafana displays it: the text of a value mapping or the value
+	// in the panel unit, e.g. 1.23 GB
+	FormattedValue string `json:"formatted_value,omitempty"`
+	// Color is the color of a value mapping or, failing that, of the threshold step
+	Color string `json:"color,omitempty"`
 }
 
-type panelMap map[int]Panel
+// Snapshot is a frozen dashboard with its data that can be viewed without Grafana credentials.
+type Snapshot struct {
+	ID   int    `json:"id"`
+	Key  string `json:"key"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// DeleteKey and DeleteURL are only known when the snapshot is created
+	DeleteKey string `json:"delete_key,omitempty"`
+	DeleteURL string `json:"delete_url,omitempty"`
+	External  bool   `json:"external,omitempty"`
+	// Expires is zero for snapshots that never expire
+	Expires time.Time `json:"expires"`
+	Created time.Time `json:"created"`
+}
 
-func (p panelMap) ToSlice() []Panel {
-	result := make([]Panel, 0, len(p))
+// Annotation marks a moment or, with TimeEnd, a region such as a deploy or an incident.
+type Annotation struct {
+	ID int64 `json:"id,omitempty"`
+	// DashboardUID and PanelID scope the annotation, they are empty for organization-wide ones
+	DashboardUID string    `json:"dashboard_uid,omitempty"`
+	PanelID      int       `json:"panel_id,omitempty"`
+	Time         time.Time `json:"time"`
+	// TimeEnd is zero for annotations of a single moment
+	TimeEnd time.Time `json:"time_end"`
+	Text    string    `json:"text"`
+	Tags    []string  `json:"tags,omitempty"`
+	// AlertID and State are set for alert state changes
+	AlertID int64  `json:"alert_id,omitempty"`
+	State   string `json:"state,omitempty"`
+}
 
-	for _, panel := range p {
-		result = append(result, panel)
+// Applies reports whether Grafana shows the annotation on the panel: organization-wide annotations
+// apply to every panel, dashboard ones to every panel of the dashboard.
+func (a Annotation) Applies(p Panel) bool {
+	if a.DashboardUID == "" {
+		return true
 	}
 
-	return result
+	return a.DashboardUID == p.DashboardUID && (a.PanelID == 0 || a.PanelID == p.ID)
+}
+
+// AnnotationQuery filters annotations. Zero fields do not filter.
+type AnnotationQuery struct {
+	DashboardUID string
+	PanelID      int
+	From         time.Time
+	To           time.Time
+	// Tags must all be on an annotation unless MatchAny is set
+	Tags     []string
+	MatchAny bool
+	// Type is AnnotationTypeAnnotation or AnnotationTypeAlert
+	Type  string
+	Limit int
+}
+
+// Org is an organization the credentials can access.
+type Org struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// Role is the role of the credentials in the organization: Admin, Editor or Viewer
+	Role string `json:"role"`
+}
+
+// NotificationRef names a legacy notification channel by UID or, in older dashboards, by ID.
+type NotificationRef struct {
+	UID string `json:"uid,omitempty"`
+	ID  int64  `json:"id,omitempty"`
+}
+
+// NotificationChannel is a legacy alerting notification channel.
+type NotificationChannel struct {
+	ID   int64  `json:"id"`
+	UID  string `json:"uid"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// IsDefault channels are notified by every alert
+	IsDefault             bool   `json:"is_default"`
+	SendReminder          bool   `json:"send_reminder"`
+	DisableResolveMessage bool   `json:"disable_resolve_message"`
+	Frequency             string `json:"frequency,omitempty"`
+	// Settings are specific to Type, secrets are not returned
+	Settings map[string]interface{} `json:"settings,omitempty"`
+}
+
+// ContactPoint is an integration of a unified alerting contact point.
+type ContactPoint struct {
+	UID                   string                 `json:"uid"`
+	Name                  string                 `json:"name"`
+	Type                  string                 `json:"type"`
+	DisableResolveMessage bool                   `json:"disable_resolve_message"`
+	Settings              map[string]interface{} `json:"settings,omitempty"`
+}
+
+// AlertRoute is where the alert of a panel is sent.
+type AlertRoute struct {
+	PanelID  int                   `json:"panel_id"`
+	Title    string                `json:"title"`
+	Alert    string                `json:"alert"`
+	Channels []NotificationChannel `json:"channels"`
+	// Missing are the references of the alert to channels that do not exist
+	Missing []NotificationRef `json:"missing,omitempty"`
+}
+
+type Series struct {
+	Label  string  `json:"label"`
+	Points []Point `json:"points"`
+}
+
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value string    `json:"value"`
 }
 
 type dashboardData struct {
@@ -46,15 +203,32 @@ type dashboardData struct {
 }
 
 type panelData struct {
-	ID    int
-	Title string
-	Exprs []expr
-	Alert Alert
+	ID      int
+	Title   string
+	Type    string
+	Row     string
+	GridPos *GridPos
+	Exprs   []expr
+	Alert   Alert
+
+	LibraryPanel *LibraryPanel
+
+	// FieldConfig is how values are displayed unless one of FieldOverrides matches them
+	FieldConfig    fieldConfig
+	FieldOverrides []fieldOverride
+
+	// Reduce is set for panels that display a calculation over TimeRange, such as stats
+	Reduce    *reduceOptions
+	TimeRange timeRange
 }
 
 type expr struct {
-	Query        string
-	LegendFormat string
+	RefID         string
+	Query         string
+	LegendFormat  string
+	Datasource    datasourceRef
+	IntervalMs    int64
+	MaxDataPoints int64
 }
 
 type ImageAttributes struct {
@@ -0,0 +1,265 @@
+package grafana
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	alertNotificationsPath     = "/api/alert-notifications"
+	alertNotificationsTestPath = "/api/alert-notifications/test"
+	contactPointsPath          = "/api/v1/provisioning/contact-points"
+	receiversTestPath          = "/api/alertmanager/grafana/config/api/v1/receivers/test"
+
+	receiverTestFailed = "failed"
+
+	// redactedSetting is the value of secure settings returned by the provisioning API
+	redactedSetting = "[REDACTED]"
+)
+
+// notificationRefDTO is an item of panel.alert.notifications.
+type notificationRefDTO struct {
+	UID string `json:"uid"`
+	ID  int64  `json:"id"`
+}
+
+type notificationChannelDTO struct {
+	ID                    int64                  `json:"id"`
+	UID                   string                 `json:"uid"`
+	Name                  string                 `json:"name"`
+	Type                  string                 `json:"type"`
+	IsDefault             bool                   `json:"isDefault"`
+	SendReminder          bool                   `json:"sendReminder"`
+	DisableResolveMessage bool                   `json:"disableResolveMessage"`
+	Frequency             string                 `json:"frequency"`
+	Settings              map[string]interface{} `json:"settings"`
+}
+
+type contactPointDTO struct {
+	UID                   string                 `json:"uid"`
+	Name                  string                 `json:"name"`
+	Type                  string                 `json:"type"`
+	DisableResolveMessage bool                   `json:"disableResolveMessage"`
+	Settings              map[string]interface{} `json:"settings"`
+}
+
+type receiversTestDTO struct {
+	Receivers []struct {
+		Name    string            `json:"name"`
+		Configs []receiverTestDTO `json:"grafana_managed_receiver_configs"`
+	} `json:"receivers"`
+}
+
+type receiverTestDTO struct {
+	contactPointDTO
+	// SecureFields are the settings Grafana takes from the stored contact point
+	SecureFields map[string]bool `json:"secureFields,omitempty"`
+	Status       string          `json:"status,omitempty"`
+	Error        string          `json:"error,omitempty"`
+}
+
+func (d notificationChannelDTO) ToNotificationChannel() NotificationChannel {
+	return NotificationChannel{
+		ID:                    d.ID,
+		UID:                   d.UID,
+		Name:                  d.Name,
+		Type:                  d.Type,
+		IsDefault:             d.IsDefault,
+		SendReminder:          d.SendReminder,
+		DisableResolveMessage: d.DisableResolveMessage,
+		Frequency:             d.Frequency,
+		Settings:              d.Settings,
+	}
+}
+
+func (d contactPointDTO) ToContactPoint() ContactPoint {
+	return ContactPoint{
+		UID:                   d.UID,
+		Name:                  d.Name,
+		Type:                  d.Type,
+		DisableResolveMessage: d.DisableResolveMessage,
+		Settings:              d.Settings,
+	}
+}
+
+// NotificationChannels returns the legacy alerting notification channels.
+func (g *grafana) NotificationChannels(ctx context.Context) ([]NotificationChannel, error) {
+	var items []notificationChannelDTO
+
+	if err := g.client.sendJSON(ctx, operationNotificationChannels, http.MethodGet, alertNotificationsPath, nil, &items); err != nil {
+		return nil, fmt.Errorf("failed to get notification channels: %w", err)
+	}
+
+	channels := make([]NotificationChannel, 0, len(items))
+	for _, item := range items {
+		channels = append(channels, item.ToNotificationChannel())
+	}
+
+	return channels, nil
+}
+
+// TestNotificationChannel sends a test notification through a legacy channel.
+func (g *grafana) TestNotificationChannel(ctx context.Context, channel NotificationChannel) error {
+	request := notificationChannelDTO{
+		ID:       channel.ID,
+		UID:      channel.UID,
+		Name:     channel.Name,
+		Type:     channel.Type,
+		Settings: channel.Settings,
+	}
+
+	if err := g.client.sendJSON(ctx, operationTestNotification, http.MethodPost, alertNotificationsTestPath, request, nil); err != nil {
+		return fmt.Errorf("failed to test notification channel %s: %w", channel.Name, err)
+	}
+
+	return nil
+}
+
+// ContactPoints returns the unified alerting contact points. A contact point with several
+// integrations is returned once per integration, with the same name.
+func (g *grafana) ContactPoints(ctx context.Context) ([]ContactPoint, error) {
+	var items []contactPointDTO
+
+	if err := g.client.sendJSON(ctx, operationContactPoints, http.MethodGet, contactPointsPath, nil, &items); err != nil {
+		return nil, fmt.Errorf("failed to get contact points: %w", err)
+	}
+
+	points := make([]ContactPoint, 0, len(items))
+	for _, item := range items {
+		points = append(points, item.ToContactPoint())
+	}
+
+	return points, nil
+}
+
+// TestContactPoint sends a test notification through a unified alerting contact point. Secure settings
+// redacted by ContactPoints are taken by Grafana from the stored contact point.
+func (g *grafana) TestContactPoint(ctx context.Context, point ContactPoint) error {
+	config := receiverTestDTO{contactPointDTO: contactPointDTO{
+		UID:                   point.UID,
+		Name:                  point.Name,
+		Type:                  point.Type,
+		DisableResolveMessage: point.DisableResolveMessage,
+		Settings:              make(map[string]interface{}, len(point.Settings)),
+	}}
+
+	for key, value := range point.Settings {
+		if value == redactedSetting {
+			if config.SecureFields == nil {
+				config.SecureFields = make(map[string]bool)
+			}
+
+			config.SecureFields[key] = true
+
+			continue
+		}
+
+		config.Settings[key] = value
+	}
+
+	var request, response receiversTestDTO
+
+	request.Receivers = append(request.Receivers, struct {
+		Name    string            `json:"name"`
+		Configs []receiverTestDTO `json:"grafana_managed_receiver_configs"`
+	}{
+		Name:    point.Name,
+		Configs: []receiverTestDTO{config},
+	})
+
+	err := g.client.sendJSON(ctx, operationTestNotification, http.MethodPost, receiversTestPath, request, &response)
+	if err == nil {
+		err = response.Err()
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to test contact point %s: %w", point.Name, err)
+	}
+
+	return nil
+}
+
+// Err returns the errors of failed integrations in a receivers test response.
+func (d receiversTestDTO) Err() error {
+	var failed []string
+
+	for _, r := range d.Receivers {
+		for _, c := range r.Configs {
+			if c.Status == receiverTestFailed {
+				failed = append(failed, fmt.Sprintf("%s: %s", c.Name, c.Error))
+			}
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("test notification failed: %s", strings.Join(failed, "; "))
+}
+
+// AlertRoutes returns the legacy channels every panel alert of the dashboard notifies: the channels
+// its notifications name and the default channels.
+func (g *grafana) AlertRoutes(ctx context.Context, dashboardUID string) ([]AlertRoute, error) {
+	ctx = withAttributes(ctx, dashboardUIDKey.String(dashboardUID))
+
+	dashboard, err := g.client.getDashboard(ctx, dashboardUID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting dashboard response: %w", err)
+	}
+
+	channels, err := g.NotificationChannels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byUID := make(map[string]NotificationChannel, len(channels))
+	byID := make(map[int64]NotificationChannel, len(channels))
+
+	for _, ch := range channels {
+		byUID[ch.UID] = ch
+		byID[ch.ID] = ch
+	}
+
+	routes := make([]AlertRoute, 0)
+
+	for _, p := range dashboard.Panels {
+		if p.Alert.Name == "" {
+			continue
+		}
+
+		route := AlertRoute{PanelID: p.ID, Title: p.Title, Alert: p.Alert.Name}
+		routed := make(map[int64]bool)
+
+		for _, ref := range p.Alert.Notifications {
+			ch, ok := byUID[ref.UID]
+			if ref.UID == "" {
+				ch, ok = byID[ref.ID]
+			}
+
+			if !ok {
+				route.Missing = append(route.Missing, ref)
+
+				continue
+			}
+
+			if !routed[ch.ID] {
+				routed[ch.ID] = true
+				route.Channels = append(route.Channels, ch)
+			}
+		}
+
+		for _, ch := range channels {
+			if ch.IsDefault && !routed[ch.ID] {
+				routed[ch.ID] = true
+				route.Channels = append(route.Channels, ch)
+			}
+		}
+
+		routes = append(routes, route)
+	}
+
+	return routes, nil
+}
@@ -0,0 +1,216 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const fakeNotificationChannels = `[
+  {"id": 1, "uid": "telegram", "name": "Telegram", "type": "telegram", "isDefault": false,
+   "sendReminder": true, "frequency": "1h", "settings": {"chatid": "-100"}},
+  {"id": 2, "uid": "oncall", "name": "On-call", "type": "pagerduty", "isDefault": true, "settings": {}},
+  {"id": 3, "uid": "email", "name": "Email", "type": "email", "isDefault": false, "settings": {}}
+]`
+
+func TestNotificationChannels(t *testing.T) {
+	f, inst := newFakeGrafanaClient(t)
+
+	f.handle(alertNotificationsPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(fakeNotificationChannels))
+	})
+
+	channels, err := inst.NotificationChannels(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := NotificationChannel{
+		ID:           1,
+		UID:          "telegram",
+		Name:         "Telegram",
+		Type:         "telegram",
+		SendReminder: true,
+		Frequency:    "1h",
+		Settings:     map[string]interface{}{"chatid": "-100"},
+	}
+
+	if len(channels) != 3 || !reflect.DeepEqual(channels[0], want) || !channels[1].IsDefault {
+		t.Fatalf("wrong channels: %+v", channels)
+	}
+}
+
+func TestTestNotificationChannel(t *testing.T) {
+	f, inst := newFakeGrafanaClient(t)
+
+	var got notificationChannelDTO
+
+	f.handle(alertNotificationsTestPath, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("malformed test request: %s", err)
+		}
+
+		if got.Type == "email" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"Failed to send test alert notification"}`))
+
+			return
+		}
+
+		w.Write([]byte(`{"message":"Test notification sent"}`))
+	})
+
+	channel := NotificationChannel{ID: 1, UID: "telegram", Name: "Telegram", Type: "telegram", Settings: map[string]interface{}{"chatid": "-100"}}
+
+	if err := inst.TestNotificationChannel(context.Background(), channel); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.ID != 1 || got.Type != "telegram" || got.Settings["chatid"] != "-100" {
+		t.Fatalf("wrong test request: %+v", got)
+	}
+
+	err := inst.TestNotificationChannel(context.Background(), NotificationChannel{ID: 3, Name: "Email", Type: "email"})
+	if err == nil || !strings.Contains(err.Error(), "Failed to send test alert notification") {
+		t.Fatalf("expected the Grafana message, got %v", err)
+	}
+}
+
+func TestContactPoints(t *testing.T) {
+	f, inst := newFakeGrafanaClient(t)
+
+	f.handle(contactPointsPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"uid": "cp1", "name": "ops", "type": "slack", "disableResolveMessage": true,
+			"settings": {"recipient": "#ops"}}]`))
+	})
+
+	points, err := inst.ContactPoints(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ContactPoint{{
+		UID:                   "cp1",
+		Name:                  "ops",
+		Type:                  "slack",
+		DisableResolveMessage: true,
+		Settings:              map[string]interface{}{"recipient": "#ops"},
+	}}
+
+	if !reflect.DeepEqual(points, want) {
+		t.Fatalf("wrong contact points: %+v", points)
+	}
+}
+
+func TestTestContactPoint(t *testing.T) {
+	f, inst := newFakeGrafanaClient(t)
+
+	f.handle(receiversTestPath, func(w http.ResponseWriter, r *http.Request) {
+		var request receiversTestDTO
+
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &request); err != nil || len(request.Receivers) != 1 || len(request.Receivers[0].Configs) != 1 {
+			t.Errorf("malformed test request: %s", body)
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		config := request.Receivers[0].Configs[0]
+
+		// the redacted webhook url is taken by Grafana from the stored contact point
+		if config.Type == "slack" {
+			if _, ok := config.Settings["url"]; ok || !config.SecureFields["url"] || config.Settings["recipient"] != "#ops" {
+				t.Errorf("redacted setting is sent: %s", body)
+			}
+		}
+
+		if config.Type == "email" {
+			w.Write([]byte(`<html>proxy error</html>`))
+
+			return
+		}
+
+		if config.Type == "webhook" {
+			w.WriteHeader(http.StatusMultiStatus)
+			w.Write([]byte(`{"receivers":[{"name":"hook","grafana_managed_receiver_configs":[
+				{"uid":"cp2","name":"hook","status":"failed","error":"connection refused"}]}]}`))
+
+			return
+		}
+
+		w.Write([]byte(`{"receivers":[{"name":"ops","grafana_managed_receiver_configs":[
+			{"uid":"cp1","name":"ops","status":"ok"}]}]}`))
+	})
+
+	slack := ContactPoint{
+		UID:      "cp1",
+		Name:     "ops",
+		Type:     "slack",
+		Settings: map[string]interface{}{"recipient": "#ops", "url": redactedSetting},
+	}
+
+	if err := inst.TestContactPoint(context.Background(), slack); err != nil {
+		t.Fatal(err)
+	}
+
+	err := inst.TestContactPoint(context.Background(), ContactPoint{UID: "cp2", Name: "hook", Type: "webhook"})
+	if err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("expected the integration error, got %v", err)
+	}
+
+	if err = inst.TestContactPoint(context.Background(), ContactPoint{UID: "cp3", Name: "mail", Type: "email"}); err == nil {
+		t.Fatal("expected an error for a malformed response")
+	}
+}
+
+func TestAlertRoutes(t *testing.T) {
+	f, inst := newFakeGrafanaClient(t)
+
+	f.handle(alertNotificationsPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(fakeNotificationChannels))
+	})
+
+	routes, err := inst.AlertRoutes(context.Background(), fakeDashboardUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(routes) != 1 {
+		t.Fatalf("expected a route of the alerting panel, got %+v", routes)
+	}
+
+	route := routes[0]
+	if route.PanelID != 4 || route.Alert != "Jailed validators alert" || len(route.Missing) != 0 {
+		t.Fatalf("wrong route: %+v", route)
+	}
+
+	var names []string
+	for _, ch := range route.Channels {
+		names = append(names, ch.Name)
+	}
+
+	// the referenced channel, then the default one
+	if !reflect.DeepEqual(names, []string{"Telegram", "On-call"}) {
+		t.Fatalf("wrong channels: %v", names)
+	}
+
+	// the referenced channel is gone
+	f.handle(alertNotificationsPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	})
+
+	routes, err = inst.AlertRoutes(context.Background(), fakeDashboardUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(routes[0].Channels) != 0 || !reflect.DeepEqual(routes[0].Missing, []NotificationRef{{UID: "telegram"}}) {
+		t.Fatalf("wrong route without channels: %+v", routes[0])
+	}
+}
@@ -0,0 +1,85 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+
+	"github.com/lidofinance/grafana-monitors-client/report"
+)
+
+const (
+	// https://discord.com/developers/docs/resources/webhook#execute-webhook
+	discordMaxContentLength = 2000
+	// upload limit of servers without boosts
+	discordMaxFileSize = 10 << 20
+)
+
+// Discord posts panels to a webhook with the image attached. Images above the upload limit are left out.
+type Discord struct {
+	options
+
+	webhookURL string
+}
+
+func NewDiscord(webhookURL string, opts ...Option) (*Discord, error) {
+	o, err := newOptions(report.Markdown, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Discord{
+		options:    o,
+		webhookURL: webhookURL,
+	}, nil
+}
+
+func (d *Discord) Notify(ctx context.Context, msg Message) error {
+	text, err := d.text(msg.Panel)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"content": truncate(text, discordMaxContentLength),
+	})
+	if err != nil {
+		return fmt.Errorf("discord: failed to marshal payload: %w", err)
+	}
+
+	attach := len(msg.Image) > 0 && len(msg.Image) <= discordMaxFileSize
+
+	write := func(w io.Writer) (string, error) {
+		mw := multipart.NewWriter(w)
+
+		if err := mw.WriteField("payload_json", string(payload)); err != nil {
+			return "", err
+		}
+
+		if attach {
+			fw, err := mw.CreateFormFile("files[0]", imageFileName)
+			if err != nil {
+				return "", err
+			}
+
+			if _, err = fw.Write(msg.Image); err != nil {
+				return "", err
+			}
+		}
+
+		return mw.FormDataContentType(), mw.Close()
+	}
+
+	status, body, err := d.do(ctx, multipartRequest(d.webhookURL, write))
+	if err != nil {
+		return fmt.Errorf("discord: %w", err)
+	}
+
+	if status < 200 || status >= 300 {
+		return fmt.Errorf("discord: status code is %d: %s", status, body)
+	}
+
+	return nil
+}
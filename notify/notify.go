@@ -0,0 +1,283 @@
+// Package notify posts panels with their rendered images to chats.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	grafana "github.com/lidofinance/grafana-monitors-client"
+	"github.com/lidofinance/grafana-monitors-client/report"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultRetryAfter = time.Second
+	defaultTimeout    = 30 * time.Second
+
+	retryAfterHeader = "Retry-After"
+	imageFileName    = "panel.png"
+	ellipsis         = "…"
+)
+
+// Message is a panel to be posted. Image is the PNG rendered by Grafana and may be nil.
+type Message struct {
+	Panel grafana.Panel
+	Image []byte
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// ImageUser is implemented by notifiers that may not post images, so Send can skip rendering them.
+type ImageUser interface {
+	UsesImages() bool
+}
+
+// Send posts the panels one by one. The image of every panel is rendered with GetPanelPicture unless
+// the notifier does not use images; a panel whose image fails to render is posted without it.
+func Send(ctx context.Context, g grafana.Grafana, n Notifier, panels ...grafana.Panel) error {
+	render := true
+	if u, ok := n.(ImageUser); ok {
+		render = u.UsesImages()
+	}
+
+	for _, p := range panels {
+		var image []byte
+
+		if render {
+			// the text still carries the alert, so a renderer outage must not silence it
+			image, _ = g.GetPanelPicture(p.Image)
+		}
+
+		if err := n.Notify(ctx, Message{Panel: p, Image: image}); err != nil {
+			return fmt.Errorf("failed to notify about panel %s: %w", p.Title, err)
+		}
+	}
+
+	return nil
+}
+
+type Option func(o *options)
+
+type options struct {
+	client     *http.Client
+	renderer   *report.Renderer
+	maxRetries int
+	apiURL     string
+}
+
+// WithHTTPClient sets the client used to call chat APIs.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// WithRenderer sets the renderer of message text. Every notifier defaults to the format its platform displays best.
+func WithRenderer(renderer *report.Renderer) Option {
+	return func(o *options) {
+		o.renderer = renderer
+	}
+}
+
+// WithMaxRetries sets how many times a rate limited request is retried, 3 by default.
+func WithMaxRetries(n int) Option {
+	return func(o *options) {
+		o.maxRetries = n
+	}
+}
+
+// WithAPIURL overrides the Telegram Bot API or Slack Web API address, e.g. for a local Bot API server.
+func WithAPIURL(url string) Option {
+	return func(o *options) {
+		o.apiURL = url
+	}
+}
+
+func newOptions(format report.Format, opts []Option) (options, error) {
+	o := options{
+		client:     &http.Client{Timeout: defaultTimeout},
+		maxRetries: defaultMaxRetries,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.renderer == nil {
+		renderer, err := report.New(format)
+		if err != nil {
+			return options{}, err
+		}
+
+		o.renderer = renderer
+	}
+
+	return o, nil
+}
+
+func (o options) text(panel grafana.Panel) (string, error) {
+	text, err := o.renderer.RenderString("", []grafana.Panel{panel})
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(text), nil
+}
+
+// do sends the request built by newRequest and retries it while the platform answers 429 Too Many Requests.
+// The response body is returned along with the status code of the last attempt.
+func (o options) do(ctx context.Context, newRequest func(ctx context.Context) (*http.Request, error)) (int, []byte, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest(ctx)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, err := o.client.Do(req)
+		if err != nil {
+			// url.Error repeats the request URL, which holds the Telegram bot token
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				err = urlErr.Err
+			}
+
+			return 0, nil, fmt.Errorf("failed to do request: %w", err)
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+
+		if resp.StatusCode != http.StatusTooManyRequests || attempt >= o.maxRetries {
+			return resp.StatusCode, body, nil
+		}
+
+		timer := time.NewTimer(retryAfter(resp.Header, body))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return 0, nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryAfter reads the delay from the Retry-After header, Discord's retry_after or Telegram's parameters.retry_after.
+func retryAfter(header http.Header, body []byte) time.Duration {
+	if seconds, err := strconv.ParseFloat(header.Get(retryAfterHeader), 64); err == nil {
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	var payload struct {
+		RetryAfter *float64 `json:"retry_after"`
+		Parameters struct {
+			RetryAfter *float64 `json:"retry_after"`
+		} `json:"parameters"`
+	}
+
+	if err := json.Unmarshal(body, &payload); err == nil {
+		switch {
+		case payload.RetryAfter != nil:
+			return time.Duration(*payload.RetryAfter * float64(time.Second))
+		case payload.Parameters.RetryAfter != nil:
+			return time.Duration(*payload.Parameters.RetryAfter * float64(time.Second))
+		}
+	}
+
+	return defaultRetryAfter
+}
+
+func jsonRequest(url string, payload interface{}) func(ctx context.Context) (*http.Request, error) {
+	return func(ctx context.Context) (*http.Request, error) {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+
+		return req, nil
+	}
+}
+
+func formRequest(url string, form url.Values) func(ctx context.Context) (*http.Request, error) {
+	return func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		return req, nil
+	}
+}
+
+// withBearer authorizes the requests built by newRequest with the token.
+func withBearer(newRequest func(ctx context.Context) (*http.Request, error), token string) func(ctx context.Context) (*http.Request, error) {
+	return func(ctx context.Context) (*http.Request, error) {
+		req, err := newRequest(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		return req, nil
+	}
+}
+
+// multipartRequest builds the body on every attempt since a sent body cannot be rewound.
+func multipartRequest(url string, write func(w io.Writer) (string, error)) func(ctx context.Context) (*http.Request, error) {
+	return func(ctx context.Context) (*http.Request, error) {
+		var body bytes.Buffer
+
+		contentType, err := write(&body)
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &body)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Content-Type", contentType)
+
+		return req, nil
+	}
+}
+
+// truncate cuts s to at most limit characters, marking the cut with an ellipsis.
+func truncate(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+
+	runes := []rune(s)
+
+	return string(runes[:limit-utf8.RuneCountInString(ellipsis)]) + ellipsis
+}
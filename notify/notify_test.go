@@ -0,0 +1,433 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	grafana "github.com/lidofinance/grafana-monitors-client"
+	"github.com/lidofinance/grafana-monitors-client/report"
+)
+
+var testImage = []byte("\x89PNG\r\n\x1a\nfake")
+
+func testPanel() grafana.Panel {
+	return grafana.Panel{
+		Title: "Slashing: Jailed Validators",
+		Image: "http://grafana/render/d-solo/monitors/lido-monitors?panelId=4",
+		Alert: grafana.Alert{Name: "Jailed validators alert", State: "alerting"},
+		CurrentValues: []grafana.CurrentValue{
+			{Values: []grafana.LabelValue{{Label: "jailed", Value: "2"}}},
+		},
+	}
+}
+
+// request is what a stand-in server received.
+type request struct {
+	path   string
+	fields map[string]string
+	files  map[string][]byte
+	json   map[string]interface{}
+}
+
+// standIn records requests and answers 429 to the first rateLimited of them.
+type standIn struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	requests    []request
+	rateLimited int
+	rateLimit   func(w http.ResponseWriter)
+	respond     func(w http.ResponseWriter)
+}
+
+func newStandIn(t *testing.T, respond func(w http.ResponseWriter)) *standIn {
+	s := &standIn{respond: respond}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.rateLimited > 0 {
+			s.rateLimited--
+			s.rateLimit(w)
+
+			return
+		}
+
+		req := request{
+			path:   r.URL.Path,
+			fields: make(map[string]string),
+			files:  make(map[string][]byte),
+		}
+
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				t.Errorf("failed to parse multipart form: %s", err)
+			}
+
+			for name, values := range r.MultipartForm.Value {
+				req.fields[name] = values[0]
+			}
+
+			for name, files := range r.MultipartForm.File {
+				f, _ := files[0].Open()
+				req.files[name], _ = ioutil.ReadAll(f)
+				f.Close()
+			}
+		} else if err := json.NewDecoder(r.Body).Decode(&req.json); err != nil {
+			t.Errorf("failed to decode json: %s", err)
+		}
+
+		s.requests = append(s.requests, req)
+		s.respond(w)
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func telegramOK(w http.ResponseWriter) {
+	w.Write([]byte(`{"ok":true,"result":{}}`))
+}
+
+func TestTelegram(t *testing.T) {
+	s := newStandIn(t, telegramOK)
+	s.rateLimited = 1
+	s.rateLimit = func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"ok":false,"error_code":429,"description":"Too Many Requests","parameters":{"retry_after":0}}`))
+	}
+
+	n, err := NewTelegram("123:secret", "-100", WithAPIURL(s.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = n.Notify(context.Background(), Message{Panel: testPanel(), Image: testImage}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(s.requests))
+	}
+
+	req := s.requests[0]
+	if req.path != "/bot123:secret/sendPhoto" || req.fields["chat_id"] != "-100" || !bytes.Equal(req.files["photo"], testImage) {
+		t.Fatalf("unexpected request %+v", req)
+	}
+
+	if !strings.Contains(req.fields["caption"], "[ALERTING] Slashing: Jailed Validators") {
+		t.Fatalf("unexpected caption %q", req.fields["caption"])
+	}
+}
+
+func TestTelegramLongText(t *testing.T) {
+	s := newStandIn(t, telegramOK)
+
+	// without truncation in the report the text exceeds both caption and message limits
+	renderer, err := report.New(report.Text, report.WithMaxValues(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := NewTelegram("token", "-100", WithAPIURL(s.URL), WithRenderer(renderer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	panel := testPanel()
+	for i := 0; i < 500; i++ {
+		panel.CurrentValues[0].Values = append(panel.CurrentValues[0].Values, grafana.LabelValue{Label: "validator", Value: "1"})
+	}
+
+	if err = n.Notify(context.Background(), Message{Panel: panel, Image: testImage}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.requests) != 2 {
+		t.Fatalf("got %d requests, want photo and message", len(s.requests))
+	}
+
+	if s.requests[0].fields["caption"] != panel.Title {
+		t.Fatalf("caption is not the title: %q", s.requests[0].fields["caption"])
+	}
+
+	text := s.requests[1].json["text"].(string)
+	if len([]rune(text)) > telegramMaxMessageLength || !strings.HasSuffix(text, ellipsis) {
+		t.Fatalf("message is not truncated to the limit: %d", len([]rune(text)))
+	}
+}
+
+func TestTelegramError(t *testing.T) {
+	s := newStandIn(t, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`))
+	})
+
+	n, err := NewTelegram("token", "-100", WithAPIURL(s.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.Notify(context.Background(), Message{Panel: testPanel()})
+	if err == nil || !strings.Contains(err.Error(), "chat not found") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestSlack(t *testing.T) {
+	s := newStandIn(t, func(w http.ResponseWriter) { w.Write([]byte("ok")) })
+	s.rateLimited = 2
+	s.rateLimit = func(w http.ResponseWriter) {
+		w.Header().Set(retryAfterHeader, "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}
+
+	n, err := NewSlack(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = n.Notify(context.Background(), Message{Panel: testPanel(), Image: testImage}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.requests) != 1 || !strings.Contains(s.requests[0].json["text"].(string), "jailed: 2") {
+		t.Fatalf("unexpected requests %+v", s.requests)
+	}
+}
+
+func TestSlackApp(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		uploaded []byte
+		complete map[string]interface{}
+		auth     []string
+	)
+
+	var server *httptest.Server
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/" + slackGetUploadURLMethod:
+			auth = append(auth, r.Header.Get("Authorization"))
+
+			if r.FormValue("filename") != imageFileName || r.FormValue("length") != strconv.Itoa(len(testImage)) {
+				t.Errorf("unexpected upload form %v", r.Form)
+			}
+
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "upload_url": server.URL + "/upload", "file_id": "F1"})
+		case "/upload":
+			f, _, err := r.FormFile("file")
+			if err != nil {
+				t.Errorf("no file uploaded: %s", err)
+				w.WriteHeader(http.StatusBadRequest)
+
+				return
+			}
+
+			uploaded, _ = ioutil.ReadAll(f)
+			w.Write([]byte("OK"))
+		case "/" + slackCompleteUploadMethod:
+			auth = append(auth, r.Header.Get("Authorization"))
+			json.NewDecoder(r.Body).Decode(&complete)
+			w.Write([]byte(`{"ok":true}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	n, err := NewSlackApp("xoxb-token", "C123", WithAPIURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = n.Notify(context.Background(), Message{Panel: testPanel(), Image: testImage}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(uploaded, testImage) {
+		t.Fatal("image is not uploaded")
+	}
+
+	if complete["channel_id"] != "C123" || !strings.Contains(complete["initial_comment"].(string), "jailed: 2") {
+		t.Fatalf("unexpected upload completion %v", complete)
+	}
+
+	for _, a := range auth {
+		if a != "Bearer xoxb-token" {
+			t.Fatalf("unexpected authorization %q", a)
+		}
+	}
+}
+
+func TestSlackAppWithoutImage(t *testing.T) {
+	s := newStandIn(t, func(w http.ResponseWriter) { w.Write([]byte(`{"ok":true}`)) })
+
+	n, err := NewSlackApp("xoxb-token", "C123", WithAPIURL(s.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = n.Notify(context.Background(), Message{Panel: testPanel()}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := s.requests[0]
+	if req.path != "/"+slackPostMessageMethod || req.json["channel"] != "C123" {
+		t.Fatalf("unexpected request %+v", req)
+	}
+
+	s.respond = func(w http.ResponseWriter) { w.Write([]byte(`{"ok":false,"error":"channel_not_found"}`)) }
+
+	err = n.Notify(context.Background(), Message{Panel: testPanel()})
+	if err == nil || !strings.Contains(err.Error(), "channel_not_found") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestRateLimitRetriesExhausted(t *testing.T) {
+	s := newStandIn(t, func(w http.ResponseWriter) { w.Write([]byte("ok")) })
+	s.rateLimited = 3
+	s.rateLimit = func(w http.ResponseWriter) {
+		w.Header().Set(retryAfterHeader, "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}
+
+	n, err := NewSlack(s.URL, WithMaxRetries(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = n.Notify(context.Background(), Message{Panel: testPanel()}); err == nil {
+		t.Fatal("expected error after retries are exhausted")
+	}
+}
+
+func TestDiscord(t *testing.T) {
+	s := newStandIn(t, func(w http.ResponseWriter) { w.WriteHeader(http.StatusNoContent) })
+	s.rateLimited = 1
+	s.rateLimit = func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"message":"You are being rate limited.","retry_after":0.001,"global":false}`))
+	}
+
+	n, err := NewDiscord(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = n.Notify(context.Background(), Message{Panel: testPanel(), Image: testImage}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := s.requests[0]
+	if !bytes.Equal(req.files["files[0]"], testImage) {
+		t.Fatal("image is not attached")
+	}
+
+	var payload struct {
+		Content string `json:"content"`
+	}
+
+	if err = json.Unmarshal([]byte(req.fields["payload_json"]), &payload); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(payload.Content, "🔴 **Slashing: Jailed Validators**") {
+		t.Fatalf("unexpected content %q", payload.Content)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	s := newStandIn(t, func(w http.ResponseWriter) { w.WriteHeader(http.StatusAccepted) })
+
+	n, err := NewWebhook(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = n.Notify(context.Background(), Message{Panel: testPanel(), Image: testImage}); err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := json.Marshal(s.requests[0].json)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var payload WebhookPayload
+	if err = json.Unmarshal(body, &payload); err != nil {
+		t.Fatal(err)
+	}
+
+	if payload.Panel.Title != testPanel().Title || !bytes.Equal(payload.Image, testImage) || payload.Text == "" {
+		t.Fatalf("unexpected payload %+v", payload)
+	}
+}
+
+func TestSend(t *testing.T) {
+	s := newStandIn(t, func(w http.ResponseWriter) { w.WriteHeader(http.StatusOK) })
+
+	n, err := NewWebhook(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl := gomock.NewController(t)
+	g := grafana.NewMockGrafana(ctrl)
+	g.EXPECT().GetPanelPicture(testPanel().Image).Return(testImage, nil).Times(2)
+
+	if err = Send(context.Background(), g, n, testPanel(), testPanel()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(s.requests))
+	}
+}
+
+func TestSendWithoutImage(t *testing.T) {
+	s := newStandIn(t, func(w http.ResponseWriter) { w.WriteHeader(http.StatusOK) })
+
+	n, err := NewWebhook(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl := gomock.NewController(t)
+	g := grafana.NewMockGrafana(ctrl)
+	g.EXPECT().GetPanelPicture(testPanel().Image).Return(nil, errors.New("renderer is down")).Times(2)
+
+	if err = Send(context.Background(), g, n, testPanel(), testPanel()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.requests) != 2 || s.requests[0].json["image"] != nil {
+		t.Fatalf("expected panels without images, got %+v", s.requests)
+	}
+
+	// webhooks can't attach images, so none are rendered
+	slack, err := NewSlack(newStandIn(t, func(w http.ResponseWriter) { w.Write([]byte("ok")) }).URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = Send(context.Background(), g, slack, testPanel()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,199 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/lidofinance/grafana-monitors-client/report"
+)
+
+const (
+	slackAPIURL = "https://slack.com/api"
+
+	// https://api.slack.com/reference/messaging/payload
+	slackMaxTextLength = 40000
+
+	slackPostMessageMethod    = "chat.postMessage"
+	slackGetUploadURLMethod   = "files.getUploadURLExternal"
+	slackCompleteUploadMethod = "files.completeUploadExternal"
+)
+
+// Slack posts panels to an incoming webhook or, created with NewSlackApp, through the Slack Web API.
+// Incoming webhooks cannot carry files, so only the app uploads the rendered image; webhook
+// messages keep the link to the panel image.
+type Slack struct {
+	options
+
+	webhookURL string
+
+	token   string
+	channel string
+}
+
+func NewSlack(webhookURL string, opts ...Option) (*Slack, error) {
+	o, err := newOptions(report.Text, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Slack{
+		options:    o,
+		webhookURL: webhookURL,
+	}, nil
+}
+
+// NewSlackApp posts to the channel id with a bot token that has the chat:write and files:write scopes.
+// Images are uploaded along with the text.
+func NewSlackApp(token string, channel string, opts ...Option) (*Slack, error) {
+	o, err := newOptions(report.Text, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.apiURL == "" {
+		o.apiURL = slackAPIURL
+	}
+
+	return &Slack{
+		options: o,
+		token:   token,
+		channel: channel,
+	}, nil
+}
+
+// UsesImages is false for webhooks, which cannot attach the image.
+func (s *Slack) UsesImages() bool {
+	return s.token != ""
+}
+
+func (s *Slack) Notify(ctx context.Context, msg Message) error {
+	text, err := s.text(msg.Panel)
+	if err != nil {
+		return err
+	}
+
+	text = truncate(text, slackMaxTextLength)
+
+	if s.token == "" {
+		return s.sendWebhook(ctx, text)
+	}
+
+	if len(msg.Image) == 0 {
+		payload := map[string]interface{}{
+			"channel": s.channel,
+			"text":    text,
+		}
+
+		_, err = s.call(ctx, slackPostMessageMethod, jsonRequest(s.methodURL(slackPostMessageMethod), payload))
+
+		return err
+	}
+
+	return s.upload(ctx, msg, text)
+}
+
+func (s *Slack) sendWebhook(ctx context.Context, text string) error {
+	payload := map[string]interface{}{
+		"text": text,
+	}
+
+	status, body, err := s.do(ctx, jsonRequest(s.webhookURL, payload))
+	if err != nil {
+		return fmt.Errorf("slack: %w", err)
+	}
+
+	if status != http.StatusOK {
+		return fmt.Errorf("slack: status code is %d: %s", status, body)
+	}
+
+	return nil
+}
+
+// upload shares the image in the channel with the text as its comment: the file is sent to an
+// upload URL Slack hands out and then completed into the channel.
+func (s *Slack) upload(ctx context.Context, msg Message, text string) error {
+	var target struct {
+		UploadURL string `json:"upload_url"`
+		FileID    string `json:"file_id"`
+	}
+
+	form := url.Values{}
+	form.Set("filename", imageFileName)
+	form.Set("length", strconv.Itoa(len(msg.Image)))
+
+	body, err := s.call(ctx, slackGetUploadURLMethod, formRequest(s.methodURL(slackGetUploadURLMethod), form))
+	if err != nil {
+		return err
+	}
+
+	if err = json.Unmarshal(body, &target); err != nil {
+		return fmt.Errorf("slack %s: failed to unmarshal response: %w", slackGetUploadURLMethod, err)
+	}
+
+	write := func(w io.Writer) (string, error) {
+		mw := multipart.NewWriter(w)
+
+		fw, err := mw.CreateFormFile("file", imageFileName)
+		if err != nil {
+			return "", err
+		}
+
+		if _, err = fw.Write(msg.Image); err != nil {
+			return "", err
+		}
+
+		return mw.FormDataContentType(), mw.Close()
+	}
+
+	status, body, err := s.do(ctx, multipartRequest(target.UploadURL, write))
+	if err != nil {
+		return fmt.Errorf("slack upload: %w", err)
+	}
+
+	if status != http.StatusOK {
+		return fmt.Errorf("slack upload: status code is %d: %s", status, body)
+	}
+
+	payload := map[string]interface{}{
+		"files":           []map[string]string{{"id": target.FileID, "title": msg.Panel.Title}},
+		"channel_id":      s.channel,
+		"initial_comment": text,
+	}
+
+	_, err = s.call(ctx, slackCompleteUploadMethod, jsonRequest(s.methodURL(slackCompleteUploadMethod), payload))
+
+	return err
+}
+
+// call invokes a Web API method with the bot token and returns the response body once Slack reports it ok.
+func (s *Slack) call(ctx context.Context, method string, newRequest func(ctx context.Context) (*http.Request, error)) ([]byte, error) {
+	var response struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+
+	status, body, err := s.do(ctx, withBearer(newRequest, s.token))
+	if err != nil {
+		return nil, fmt.Errorf("slack %s: %w", method, err)
+	}
+
+	if err = json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("slack %s: status code is %d: failed to unmarshal response: %w", method, status, err)
+	}
+
+	if !response.OK {
+		return nil, fmt.Errorf("slack %s: status code is %d: %s", method, status, response.Error)
+	}
+
+	return body, nil
+}
+
+func (s *Slack) methodURL(method string) string {
+	return fmt.Sprintf("%s/%s", s.apiURL, method)
+}
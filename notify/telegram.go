@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/lidofinance/grafana-monitors-client/report"
+)
+
+const (
+	telegramAPIURL = "https://api.telegram.org"
+
+	// https://core.telegram.org/bots/api#sendmessage and #sendphoto
+	telegramMaxMessageLength  = 4096
+	telegramMaxCaptionLength  = 1024
+	telegramMaxPhotoSize      = 10 << 20
+	telegramMaxDocumentSize   = 50 << 20
+	telegramSendMessageMethod = "sendMessage"
+	telegramSendPhotoMethod   = "sendPhoto"
+	telegramSendDocMethod     = "sendDocument"
+)
+
+// Telegram posts panels to a chat through the Telegram Bot API.
+// Images go as photos, or as documents when they exceed the photo size limit; text longer than
+// a caption follows the image as a separate message.
+type Telegram struct {
+	options
+
+	token  string
+	chatID string
+}
+
+func NewTelegram(token string, chatID string, opts ...Option) (*Telegram, error) {
+	o, err := newOptions(report.Text, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.apiURL == "" {
+		o.apiURL = telegramAPIURL
+	}
+
+	return &Telegram{
+		options: o,
+		token:   token,
+		chatID:  chatID,
+	}, nil
+}
+
+func (t *Telegram) Notify(ctx context.Context, msg Message) error {
+	text, err := t.text(msg.Panel)
+	if err != nil {
+		return err
+	}
+
+	if len(msg.Image) == 0 || len(msg.Image) > telegramMaxDocumentSize {
+		return t.sendMessage(ctx, text)
+	}
+
+	method, field := telegramSendPhotoMethod, "photo"
+	if len(msg.Image) > telegramMaxPhotoSize {
+		method, field = telegramSendDocMethod, "document"
+	}
+
+	caption, rest := text, ""
+	if len([]rune(text)) > telegramMaxCaptionLength {
+		caption, rest = truncate(msg.Panel.Title, telegramMaxCaptionLength), text
+	}
+
+	write := func(w io.Writer) (string, error) {
+		mw := multipart.NewWriter(w)
+
+		if err := mw.WriteField("chat_id", t.chatID); err != nil {
+			return "", err
+		}
+
+		if err := mw.WriteField("caption", caption); err != nil {
+			return "", err
+		}
+
+		fw, err := mw.CreateFormFile(field, imageFileName)
+		if err != nil {
+			return "", err
+		}
+
+		if _, err = fw.Write(msg.Image); err != nil {
+			return "", err
+		}
+
+		return mw.FormDataContentType(), mw.Close()
+	}
+
+	if err = t.call(ctx, method, multipartRequest(t.methodURL(method), write)); err != nil {
+		return err
+	}
+
+	if rest == "" {
+		return nil
+	}
+
+	return t.sendMessage(ctx, rest)
+}
+
+func (t *Telegram) sendMessage(ctx context.Context, text string) error {
+	payload := map[string]interface{}{
+		"chat_id":                  t.chatID,
+		"text":                     truncate(text, telegramMaxMessageLength),
+		"disable_web_page_preview": true,
+	}
+
+	return t.call(ctx, telegramSendMessageMethod, jsonRequest(t.methodURL(telegramSendMessageMethod), payload))
+}
+
+func (t *Telegram) call(ctx context.Context, method string, newRequest func(ctx context.Context) (*http.Request, error)) error {
+	var response struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+
+	status, body, err := t.do(ctx, newRequest)
+	if err != nil {
+		return fmt.Errorf("telegram %s: %w", method, err)
+	}
+
+	if err = json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("telegram %s: status code is %d: failed to unmarshal response: %w", method, status, err)
+	}
+
+	if !response.OK {
+		return fmt.Errorf("telegram %s: status code is %d: %s", method, status, response.Description)
+	}
+
+	return nil
+}
+
+func (t *Telegram) methodURL(method string) string {
+	return fmt.Sprintf("%s/bot%s/%s", t.apiURL, t.token, method)
+}
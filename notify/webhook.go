@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	grafana "github.com/lidofinance/grafana-monitors-client"
+	"github.com/lidofinance/grafana-monitors-client/report"
+)
+
+// WebhookPayload is the JSON body posted by Webhook. Image is base64 encoded by encoding/json.
+type WebhookPayload struct {
+	Panel grafana.Panel `json:"panel"`
+	Text  string        `json:"text"`
+	Image []byte        `json:"image,omitempty"`
+}
+
+// Webhook posts panels as JSON to any URL.
+type Webhook struct {
+	options
+
+	url string
+}
+
+func NewWebhook(url string, opts ...Option) (*Webhook, error) {
+	o, err := newOptions(report.Text, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Webhook{
+		options: o,
+		url:     url,
+	}, nil
+}
+
+func (wh *Webhook) Notify(ctx context.Context, msg Message) error {
+	text, err := wh.text(msg.Panel)
+	if err != nil {
+		return err
+	}
+
+	payload := WebhookPayload{
+		Panel: msg.Panel,
+		Text:  text,
+		Image: msg.Image,
+	}
+
+	status, body, err := wh.do(ctx, jsonRequest(wh.url, payload))
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+
+	if status < 200 || status >= 300 {
+		return fmt.Errorf("webhook: status code is %d: %s", status, body)
+	}
+
+	return nil
+}
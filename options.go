@@ -0,0 +1,128 @@
+package grafana
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// QueryAPI selects the Grafana endpoint used to run datasource queries.
+type QueryAPI int
+
+const (
+	// QueryAPIAuto uses the datasource proxy and switches to /api/ds/query once the proxy is forbidden.
+	QueryAPIAuto QueryAPI = iota
+	// QueryAPIProxy always uses the Prometheus API behind /api/datasources/proxy.
+	QueryAPIProxy
+	// QueryAPIUnified always uses /api/ds/query.
+	QueryAPIUnified
+)
+
+// Option configures a Grafana instance created by NewGrafana.
+type Option func(g *grafana)
+
+// WithQueryAPI sets the endpoint used for datasource queries. QueryAPIAuto is the default.
+func WithQueryAPI(api QueryAPI) Option {
+	return func(g *grafana) {
+		g.client.queryAPI = api
+	}
+}
+
+// WithPrometheusURLs sends queries of the mapped datasources straight to Prometheus instead of
+// the Grafana datasource proxy. Keys are Grafana datasource uids or names, values are Prometheus
+// base URLs such as http://prometheus:9090; basic auth may be given in the URL userinfo.
+// Dashboards and alerts are still fetched from Grafana.
+func WithPrometheusURLs(urls map[string]string) Option {
+	return func(g *grafana) {
+		g.client.prometheusURLs = make(map[string]string, len(urls))
+
+		for datasource, u := range urls {
+			g.client.prometheusURLs[datasource] = strings.TrimRight(u, "/")
+		}
+	}
+}
+
+// WithTransport sets the transport of the underlying HTTP client, e.g. a Recorder or Replayer.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(g *grafana) {
+		g.client.client.Transport = transport
+	}
+}
+
+// WithTracerProvider creates a span for every request to Grafana and Prometheus: dashboards,
+// alert states, datasource queries and images. Spans carry the dashboard uid, panel id, query
+// and response status code. Tracing is disabled by default.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(g *grafana) {
+		g.telemetry.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider records the duration of every request in grafana.client.request.duration and
+// counts failed ones in grafana.client.request.errors. Metrics are disabled by default.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(g *grafana) {
+		g.telemetry.meterProvider = provider
+	}
+}
+
+// WithPropagator sets how the trace context is passed to Grafana in request headers.
+// W3C trace context and baggage are used by default.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(g *grafana) {
+		g.telemetry.propagator = propagator
+	}
+}
+
+// WithLogger logs every request with its status code and latency, and retries of failed queries.
+// Nothing is logged by default.
+func WithLogger(logger Logger) Option {
+	return func(g *grafana) {
+		g.client.logger = logger
+	}
+}
+
+// WithDebug additionally logs the request URL and the beginning of the response body at debug level.
+// Secrets in URLs are redacted, request headers are never logged.
+func WithDebug(debug bool) Option {
+	return func(g *grafana) {
+		g.client.debug = debug
+	}
+}
+
+// WithPartialResults makes Panels return every panel it could evaluate instead of failing when a query
+// fails. Failed panels and queries get their Error set and a *PanelsError is returned with the panels.
+// A failure to get the alert states is reported in the *PanelsError too; only a failure to get the
+// dashboard still fails the call. Strict mode is the default.
+func WithPartialResults(partial bool) Option {
+	return func(g *grafana) {
+		g.partialResults = partial
+	}
+}
+
+// WithRenderConcurrency limits how many images RenderPanels renders at a time, 4 by default.
+func WithRenderConcurrency(n int) Option {
+	return func(g *grafana) {
+		g.renderConcurrency = n
+	}
+}
+
+// WithRenderBucket sets the time bucket RenderPanels truncates ranges to, one minute by default.
+// Calls within a bucket render the same URLs, so they can be served from the image cache.
+func WithRenderBucket(bucket time.Duration) Option {
+	return func(g *grafana) {
+		g.renderBucket = bucket
+	}
+}
+
+// WithImageCache keeps images rendered by RenderPanels, e.g. in NewMemoryImageCache or NewDiskImageCache
+// with the render bucket as TTL. Images are not cached by default.
+func WithImageCache(cache ImageCache) Option {
+	return func(g *grafana) {
+		g.imageCache = cache
+	}
+}
@@ -0,0 +1,72 @@
+package grafana
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	userOrgsPath = "/api/user/orgs"
+
+	orgIDHeader = "X-Grafana-Org-Id"
+	orgIDParam  = "orgId"
+)
+
+type orgDTO struct {
+	OrgID int64  `json:"orgId"`
+	Name  string `json:"name"`
+	Role  string `json:"role"`
+}
+
+type orgContextKey struct{}
+
+// WithOrgID sends requests to the organization instead of the default one of the token.
+func WithOrgID(orgID int64) Option {
+	return func(g *grafana) {
+		g.client.orgID = orgID
+	}
+}
+
+// ContextWithOrgID overrides the organization of WithOrgID for the calls made with the context.
+func ContextWithOrgID(ctx context.Context, orgID int64) context.Context {
+	return context.WithValue(ctx, orgContextKey{}, orgID)
+}
+
+// Orgs returns the organizations the credentials can access.
+func (g *grafana) Orgs(ctx context.Context) ([]Org, error) {
+	var items []orgDTO
+
+	if err := g.client.sendJSON(ctx, operationOrgs, http.MethodGet, userOrgsPath, nil, &items); err != nil {
+		return nil, fmt.Errorf("failed to get organizations: %w", err)
+	}
+
+	orgs := make([]Org, 0, len(items))
+	for _, item := range items {
+		orgs = append(orgs, Org{ID: item.OrgID, Name: item.Name, Role: item.Role})
+	}
+
+	return orgs, nil
+}
+
+// org is the organization of the calls made with ctx, zero for the default one of the token.
+func (c *client) org(ctx context.Context) int64 {
+	if orgID, ok := ctx.Value(orgContextKey{}).(int64); ok {
+		return orgID
+	}
+
+	return c.orgID
+}
+
+// setOrg selects the organization of a request to Grafana. Requests to Prometheus are left alone.
+func (c *client) setOrg(req *http.Request) {
+	if !strings.HasPrefix(req.URL.String(), c.url) || req.Header.Get(orgIDHeader) != "" {
+		return
+	}
+
+	if orgID := c.org(req.Context()); orgID != 0 {
+		req.Header.Set(orgIDHeader, strconv.FormatInt(orgID, 10))
+	}
+}
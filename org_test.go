@@ -0,0 +1,108 @@
+package grafana
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOrgs(t *testing.T) {
+	f, inst := newFakeGrafanaClient(t)
+
+	f.handle(userOrgsPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"orgId":1,"name":"Mainnet","role":"Viewer"},{"orgId":2,"name":"Testnet","role":"Admin"}]`))
+	})
+
+	orgs, err := inst.Orgs(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Org{{ID: 1, Name: "Mainnet", Role: "Viewer"}, {ID: 2, Name: "Testnet", Role: "Admin"}}
+	if !reflect.DeepEqual(orgs, want) {
+		t.Fatalf("wrong orgs: %+v", orgs)
+	}
+}
+
+func TestOrgID(t *testing.T) {
+	f, inst := newFakeGrafanaClient(t, WithOrgID(2))
+
+	var (
+		mu      sync.Mutex
+		headers = make(map[string]string)
+	)
+
+	record := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		headers[r.URL.Path] = r.Header.Get(orgIDHeader)
+		mu.Unlock()
+
+		w.Write([]byte(`[]`))
+	}
+
+	f.handle(alertsPath, record)
+	f.handle(annotationsPath, record)
+
+	panels, err := inst.Panels(context.Background(), fakeDashboardUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if headers[alertsPath] != "2" {
+		t.Fatalf("expected the client organization, got %q", headers[alertsPath])
+	}
+
+	u, err := url.Parse(panels[0].Image)
+	if err != nil || u.Query().Get(orgIDParam) != "2" {
+		t.Fatalf("render URL has no organization: %s", panels[0].Image)
+	}
+
+	// the context overrides the client organization
+	if _, err = inst.Annotations(ContextWithOrgID(context.Background(), 3), AnnotationQuery{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if headers[annotationsPath] != "3" {
+		t.Fatalf("expected the context organization, got %q", headers[annotationsPath])
+	}
+
+	var renderOrg string
+
+	f.handle(u.Path, func(w http.ResponseWriter, r *http.Request) {
+		renderOrg = r.Header.Get(orgIDHeader)
+		w.Write(readFixture(t, "panel.png"))
+	})
+
+	// images are fetched from the organization they were rendered for
+	images, err := inst.RenderPanels(ContextWithOrgID(context.Background(), 3), panels[:1], ImageAttributes{Width: 100, Height: 50}, time.Time{}, time.Time{})
+	if err != nil || len(images[0]) == 0 {
+		t.Fatalf("failed to render: %v", err)
+	}
+
+	if renderOrg != "3" {
+		t.Fatalf("expected the render organization, got %q", renderOrg)
+	}
+}
+
+func TestNoOrgID(t *testing.T) {
+	f, inst := newFakeGrafanaClient(t)
+
+	header := "unset"
+
+	f.handle(annotationsPath, func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get(orgIDHeader)
+		w.Write([]byte(`[]`))
+	})
+
+	if _, err := inst.Annotations(context.Background(), AnnotationQuery{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if header != "" {
+		t.Fatalf("expected the default organization of the token, got %q", header)
+	}
+}
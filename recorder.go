@@ -0,0 +1,338 @@
+package grafana
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	cassetteVersion = 1
+
+	base64BodyEncoding = "base64"
+)
+
+// sensitiveHeaders are never written to a cassette.
+var sensitiveHeaders = []string{
+	authHeader,
+	"Cookie",
+	"Set-Cookie",
+	"Proxy-Authorization",
+}
+
+// volatileParams change on every call, e.g. the evaluation time of current values or the image time range,
+// so they are ignored when a request is matched against a recorded one.
+var volatileParams = []string{"time", "start", "end", "from", "to"}
+
+// Cassette is a recorded Grafana session.
+type Cassette struct {
+	Version      int           `json:"version"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method       string      `json:"method"`
+	URL          string      `json:"url"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+type RecordedResponse struct {
+	StatusCode   int         `json:"status_code"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+// Recorder is an http.RoundTripper that passes requests to the underlying transport and
+// records every exchange with the auth headers scrubbed. Use it with WithTransport and Save
+// the session once done.
+type Recorder struct {
+	transport http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+}
+
+// NewRecorder wraps the transport, http.DefaultTransport if nil.
+func NewRecorder(transport http.RoundTripper) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &Recorder{transport: transport}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+
+		reqBody = body
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    scrubURL(req.URL),
+			Header: scrubHeader(req.Header),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     scrubHeader(resp.Header),
+		},
+	}
+
+	interaction.Request.Body, interaction.Request.BodyEncoding = encodeBody(reqBody)
+	interaction.Response.Body, interaction.Response.BodyEncoding = encodeBody(respBody)
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, interaction)
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+// Cassette returns everything recorded so far.
+func (r *Recorder) Cassette() Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	interactions := make([]Interaction, len(r.interactions))
+	copy(interactions, r.interactions)
+
+	return Cassette{
+		Version:      cassetteVersion,
+		Interactions: interactions,
+	}
+}
+
+// Save writes the recorded session to a fixture file.
+func (r *Recorder) Save(path string) error {
+	body, err := json.MarshalIndent(r.Cassette(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cassette: %w", err)
+	}
+
+	if err = ioutil.WriteFile(path, body, 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+
+	return nil
+}
+
+// Replayer is an http.RoundTripper that answers requests from a recorded session without network access.
+// Requests are matched by method, path, query and body with time parameters ignored; repeated
+// requests get the recorded responses in order, the last one being reused once they run out.
+type Replayer struct {
+	mu        sync.Mutex
+	responses map[string][]RecordedResponse
+	served    map[string]int
+}
+
+// NewReplayer loads a session saved by Recorder.Save.
+func NewReplayer(path string) (*Replayer, error) {
+	var cassette Cassette
+
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	if err = json.Unmarshal(body, &cassette); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cassette: %w", err)
+	}
+
+	return NewCassetteReplayer(cassette)
+}
+
+// NewCassetteReplayer replays an in-memory session.
+func NewCassetteReplayer(cassette Cassette) (*Replayer, error) {
+	if cassette.Version != cassetteVersion {
+		return nil, fmt.Errorf("unsupported cassette version %d", cassette.Version)
+	}
+
+	r := &Replayer{
+		responses: make(map[string][]RecordedResponse),
+		served:    make(map[string]int),
+	}
+
+	for _, i := range cassette.Interactions {
+		u, err := url.Parse(i.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse recorded url %s: %w", i.Request.URL, err)
+		}
+
+		body, err := decodeBody(i.Request.Body, i.Request.BodyEncoding)
+		if err != nil {
+			return nil, err
+		}
+
+		key := interactionKey(i.Request.Method, u, body)
+		r.responses[key] = append(r.responses[key], i.Response)
+	}
+
+	return r, nil
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+
+		reqBody = body
+	}
+
+	key := interactionKey(req.Method, req.URL, reqBody)
+
+	r.mu.Lock()
+	responses := r.responses[key]
+	n := r.served[key]
+	r.served[key]++
+	r.mu.Unlock()
+
+	if len(responses) == 0 {
+		return nil, fmt.Errorf("no recorded response for %s %s", req.Method, scrubURL(req.URL))
+	}
+
+	if n >= len(responses) {
+		n = len(responses) - 1
+	}
+
+	recorded := responses[n]
+
+	body, err := decodeBody(recorded.Body, recorded.BodyEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	header := recorded.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// interactionKey identifies a request regardless of the Grafana address it was sent to.
+func interactionKey(method string, u *url.URL, body []byte) string {
+	q := u.Query()
+	for _, p := range volatileParams {
+		q.Del(p)
+	}
+
+	return fmt.Sprintf("%s %s?%s %s", method, u.Path, q.Encode(), stripVolatileFields(body))
+}
+
+// stripVolatileFields removes the time range from JSON bodies such as /api/ds/query requests.
+func stripVolatileFields(body []byte) []byte {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return body
+	}
+
+	for _, p := range volatileParams {
+		delete(fields, p)
+	}
+
+	// map keys are marshaled sorted, so equal bodies produce equal keys
+	stripped, err := json.Marshal(fields)
+	if err != nil {
+		return body
+	}
+
+	return stripped
+}
+
+func scrubURL(u *url.URL) string {
+	scrubbed := *u
+	scrubbed.User = nil
+
+	return scrubbed.String()
+}
+
+func scrubHeader(h http.Header) http.Header {
+	scrubbed := h.Clone()
+	for _, name := range sensitiveHeaders {
+		scrubbed.Del(name)
+	}
+
+	if len(scrubbed) == 0 {
+		return nil
+	}
+
+	return scrubbed
+}
+
+func encodeBody(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+
+	return base64.StdEncoding.EncodeToString(body), base64BodyEncoding
+}
+
+func decodeBody(body string, encoding string) ([]byte, error) {
+	switch encoding {
+	case "":
+		return []byte(body), nil
+	case base64BodyEncoding:
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode recorded body: %w", err)
+		}
+
+		return decoded, nil
+	}
+
+	return nil, fmt.Errorf("unsupported body encoding %s", encoding)
+}
@@ -0,0 +1,71 @@
+package grafana
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	recorder := NewRecorder(nil)
+	f, inst := newFakeGrafanaClient(t, WithTransport(recorder))
+
+	recorded, err := inst.Panels(context.Background(), fakeDashboardUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	if err = recorder.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(body), fakeToken) {
+		t.Fatal("auth header is not scrubbed")
+	}
+
+	// the fake is gone, so everything below is served from the cassette
+	f.Close()
+
+	replayer, err := NewReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inst = NewGrafana("http://grafana.invalid", "", timeout, ImageAttributes{}, WithTransport(replayer))
+
+	replayed, err := inst.Panels(context.Background(), fakeDashboardUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(replayed) != len(recorded) {
+		t.Fatalf("got %d panels, want %d", len(replayed), len(recorded))
+	}
+
+	sort.Slice(recorded, func(i, j int) bool { return recorded[i].Title < recorded[j].Title })
+	sort.Slice(replayed, func(i, j int) bool { return replayed[i].Title < replayed[j].Title })
+
+	for i := range replayed {
+		if replayed[i].Title != recorded[i].Title || replayed[i].Alert.State != recorded[i].Alert.State {
+			t.Fatalf("got panel %+v, want %+v", replayed[i], recorded[i])
+		}
+
+		if !reflect.DeepEqual(replayed[i].CurrentValues, recorded[i].CurrentValues) {
+			t.Fatalf("got values %+v, want %+v", replayed[i].CurrentValues, recorded[i].CurrentValues)
+		}
+	}
+
+	if _, err = inst.Panels(context.Background(), "unknown"); err == nil {
+		t.Fatal("expected error for a request missing from the cassette")
+	}
+}
@@ -0,0 +1,288 @@
+package grafana
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Reducers of reduceOptions.calcs, named as in Grafana.
+const (
+	ReduceLastNotNull   = "lastNotNull"
+	ReduceLast          = "last"
+	ReduceFirstNotNull  = "firstNotNull"
+	ReduceFirst         = "first"
+	ReduceMin           = "min"
+	ReduceMax           = "max"
+	ReduceMean          = "mean"
+	ReduceSum           = "sum"
+	ReduceCount         = "count"
+	ReduceRange         = "range"
+	ReduceDelta         = "delta"
+	ReduceDiff          = "diff"
+	ReduceChangeCount   = "changeCount"
+	ReduceDistinctCount = "distinctCount"
+	ReduceAllIsNull     = "allIsNull"
+	ReduceAllIsZero     = "allIsZero"
+)
+
+const (
+	defaultReduceCalc = ReduceLastNotNull
+	// defaultReduceLimit is the number of rows Grafana shows with all values when the limit is not set
+	defaultReduceLimit = 25
+)
+
+// reduceOptions are options.reduceOptions of stat, gauge and bar gauge panels.
+type reduceOptions struct {
+	Calcs []string
+	// Fields is the name or /regexp/ of the displayed fields, empty for all numeric fields
+	Fields string
+	// Values shows every value instead of a calculation
+	Values bool
+	// Limit is the number of rows shown with Values
+	Limit int
+}
+
+// Calc is the calculation of the displayed value; Grafana shows the first one.
+func (r reduceOptions) Calc() string {
+	if len(r.Calcs) == 0 || !isSupportedCalc(r.Calcs[0]) {
+		return defaultReduceCalc
+	}
+
+	return r.Calcs[0]
+}
+
+// limit is the number of rows shown with Values.
+func (r reduceOptions) limit() int {
+	if r.Limit <= 0 {
+		return defaultReduceLimit
+	}
+
+	return r.Limit
+}
+
+// fieldMatcher reports whether the field with the display name is shown. A /regexp/ that doesn't
+// compile doesn't narrow the fields.
+func (r reduceOptions) fieldMatcher() func(name string) bool {
+	if r.Fields == "" {
+		return func(string) bool { return true }
+	}
+
+	if !strings.HasPrefix(r.Fields, "/") {
+		return func(name string) bool { return name == r.Fields }
+	}
+
+	pattern, err := compileGrafanaRegexp(r.Fields)
+	if err != nil {
+		return func(string) bool { return true }
+	}
+
+	return pattern.MatchString
+}
+
+// seriesValues turns the series into the rows of the panel: a calculation per series, or every point
+// of every series with Values.
+func (r reduceOptions) seriesValues(series []Series) []LabelValue {
+	if !r.Values {
+		return reduceSeries(r.Calc(), series)
+	}
+
+	var values []LabelValue
+
+	for _, s := range series {
+		for _, p := range s.Points {
+			values = append(values, LabelValue{Label: s.Label, Value: p.Value})
+		}
+	}
+
+	return values
+}
+
+// display keeps the values of the shown fields and, with Values, at most limit rows of all queries.
+func (r reduceOptions) display(result []CurrentValue) []CurrentValue {
+	match := r.fieldMatcher()
+	rows := r.limit()
+
+	for i := range result {
+		values := result[i].Values[:0]
+
+		for _, v := range result[i].Values {
+			if !match(v.Label) {
+				continue
+			}
+
+			if r.Values {
+				if rows == 0 {
+					break
+				}
+
+				rows--
+			}
+
+			values = append(values, v)
+		}
+
+		result[i].Values = values
+	}
+
+	return result
+}
+
+func isSupportedCalc(calc string) bool {
+	switch calc {
+	case ReduceLastNotNull, ReduceLast, ReduceFirstNotNull, ReduceFirst, ReduceMin, ReduceMax, ReduceMean,
+		ReduceSum, ReduceCount, ReduceRange, ReduceDelta, ReduceDiff, ReduceChangeCount, ReduceDistinctCount,
+		ReduceAllIsNull, ReduceAllIsZero:
+		return true
+	}
+
+	return false
+}
+
+// reducePoints applies the calculation to the points like Grafana does for a stat with nulls ignored.
+// Points that are not numbers, including NaN, are nulls. The result is false if there is nothing to show.
+func reducePoints(calc string, points []Point) (float64, bool) {
+	values := make([]*float64, len(points))
+
+	for i, p := range points {
+		if v, err := strconv.ParseFloat(p.Value, 64); err == nil && !math.IsNaN(v) {
+			values[i] = &v
+		}
+	}
+
+	switch calc {
+	case ReduceLast:
+		if len(values) == 0 || values[len(values)-1] == nil {
+			return 0, false
+		}
+
+		return *values[len(values)-1], true
+	case ReduceFirst:
+		if len(values) == 0 || values[0] == nil {
+			return 0, false
+		}
+
+		return *values[0], true
+	case ReduceAllIsNull:
+		for _, v := range values {
+			if v != nil {
+				return 0, true
+			}
+		}
+
+		return 1, true
+	}
+
+	notNull := make([]float64, 0, len(values))
+
+	for _, v := range values {
+		if v != nil {
+			notNull = append(notNull, *v)
+		}
+	}
+
+	switch calc {
+	case ReduceCount:
+		return float64(len(notNull)), true
+	case ReduceChangeCount:
+		changes := 0
+
+		for i := 1; i < len(notNull); i++ {
+			if notNull[i] != notNull[i-1] {
+				changes++
+			}
+		}
+
+		return float64(changes), true
+	case ReduceDistinctCount:
+		distinct := make(map[float64]bool, len(notNull))
+		for _, v := range notNull {
+			distinct[v] = true
+		}
+
+		return float64(len(distinct)), true
+	case ReduceAllIsZero:
+		if len(notNull) == 0 {
+			return 0, true
+		}
+
+		for _, v := range notNull {
+			if v != 0 {
+				return 0, true
+			}
+		}
+
+		return 1, true
+	}
+
+	if len(notNull) == 0 {
+		return 0, false
+	}
+
+	switch calc {
+	case ReduceFirstNotNull:
+		return notNull[0], true
+	case ReduceMin, ReduceMax, ReduceRange:
+		min, max := notNull[0], notNull[0]
+
+		for _, v := range notNull[1:] {
+			min, max = math.Min(min, v), math.Max(max, v)
+		}
+
+		switch calc {
+		case ReduceMin:
+			return min, true
+		case ReduceMax:
+			return max, true
+		}
+
+		return max - min, true
+	case ReduceMean, ReduceSum:
+		var sum float64
+		for _, v := range notNull {
+			sum += v
+		}
+
+		if calc == ReduceMean {
+			return sum / float64(len(notNull)), true
+		}
+
+		return sum, true
+	case ReduceDelta:
+		// the increase of a counter, a drop is a counter reset
+		var delta float64
+
+		for i := 1; i < len(notNull); i++ {
+			if notNull[i] >= notNull[i-1] {
+				delta += notNull[i] - notNull[i-1]
+			} else {
+				delta += notNull[i]
+			}
+		}
+
+		return delta, true
+	case ReduceDiff:
+		return notNull[len(notNull)-1] - notNull[0], true
+	}
+
+	return notNull[len(notNull)-1], true
+}
+
+// reduceSeries turns every series into the value a stat panel displays for it.
+func reduceSeries(calc string, series []Series) []LabelValue {
+	values := make([]LabelValue, 0, len(series))
+
+	for _, s := range series {
+		v, ok := reducePoints(calc, s.Points)
+		if !ok {
+			continue
+		}
+
+		values = append(values, LabelValue{
+			Label: s.Label,
+			Value: strconv.FormatFloat(v, 'f', -1, 64),
+		})
+	}
+
+	return values
+}
@@ -0,0 +1,141 @@
+package grafana
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func points(values ...string) []Point {
+	result := make([]Point, 0, len(values))
+	for i, v := range values {
+		result = append(result, Point{Time: time.Unix(int64(i*60), 0), Value: v})
+	}
+
+	return result
+}
+
+func TestReducePoints(t *testing.T) {
+	series := points("5", "NaN", "3", "8", "2", "NaN")
+
+	tests := map[string]struct {
+		want float64
+		ok   bool
+	}{
+		ReduceLastNotNull:   {2, true},
+		ReduceLast:          {0, false},
+		ReduceFirstNotNull:  {5, true},
+		ReduceFirst:         {5, true},
+		ReduceMin:           {2, true},
+		ReduceMax:           {8, true},
+		ReduceMean:          {4.5, true},
+		ReduceSum:           {18, true},
+		ReduceCount:         {4, true},
+		ReduceRange:         {6, true},
+		ReduceDelta:         {10, true}, // 5 -> 3 is a reset adding 3, then +5, then a reset adding 2
+		ReduceDiff:          {-3, true},
+		ReduceChangeCount:   {3, true},
+		ReduceDistinctCount: {4, true},
+		ReduceAllIsNull:     {0, true},
+		ReduceAllIsZero:     {0, true},
+	}
+
+	for calc, tt := range tests {
+		got, ok := reducePoints(calc, series)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("%s: got %v %v, want %v %v", calc, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if _, ok := reducePoints(ReduceLastNotNull, points("NaN")); ok {
+		t.Error("a series of nulls has a value")
+	}
+
+	if got, _ := reducePoints(ReduceAllIsNull, points("NaN")); got != 1 {
+		t.Errorf("allIsNull of nulls is %v", got)
+	}
+}
+
+func TestReduceOptionsCalc(t *testing.T) {
+	tests := []struct {
+		calcs []string
+		want  string
+	}{
+		{nil, ReduceLastNotNull},
+		{[]string{"mean", "max"}, ReduceMean},
+		{[]string{"p95"}, ReduceLastNotNull},
+	}
+
+	for _, tt := range tests {
+		if got := (reduceOptions{Calcs: tt.calcs}).Calc(); got != tt.want {
+			t.Errorf("calc of %v is %s, want %s", tt.calcs, got, tt.want)
+		}
+	}
+}
+
+func TestReduceOptionsValues(t *testing.T) {
+	series := []Series{
+		{Label: "gas", Points: points("1", "2", "3")},
+		{Label: "fee", Points: points("4", "5")},
+	}
+
+	r := reduceOptions{Values: true, Limit: 4}
+	result := r.display([]CurrentValue{{Values: r.seriesValues(series)}})
+
+	var got []string
+	for _, v := range result[0].Values {
+		got = append(got, v.Label+"="+v.Value)
+	}
+
+	if want := "gas=1 gas=2 gas=3 fee=4"; strings.Join(got, " ") != want {
+		t.Fatalf("all values are %v, want %s", got, want)
+	}
+
+	r.Limit = 0
+	if values := r.seriesValues(manyPoints()); len(r.display([]CurrentValue{{Values: values}})[0].Values) != defaultReduceLimit {
+		t.Fatalf("all values are not limited to %d by default", defaultReduceLimit)
+	}
+}
+
+func manyPoints() []Series {
+	values := make([]string, defaultReduceLimit+5)
+	for i := range values {
+		values[i] = strconv.Itoa(i)
+	}
+
+	return []Series{{Label: "gas", Points: points(values...)}}
+}
+
+func TestReduceOptionsFields(t *testing.T) {
+	series := []Series{
+		{Label: "gas price", Points: points("1", "2")},
+		{Label: "gas limit", Points: points("3")},
+		{Label: "fee", Points: points("4")},
+	}
+
+	tests := []struct {
+		fields string
+		want   []string
+	}{
+		{"", []string{"gas price", "gas limit", "fee"}},
+		{"/^gas/", []string{"gas price", "gas limit"}},
+		{"/LIMIT/i", []string{"gas limit"}},
+		{"fee", []string{"fee"}},
+		{"/(/", []string{"gas price", "gas limit", "fee"}},
+	}
+
+	for _, tt := range tests {
+		r := reduceOptions{Fields: tt.fields}
+		result := r.display([]CurrentValue{{Values: r.seriesValues(series)}})
+
+		var got []string
+		for _, v := range result[0].Values {
+			got = append(got, v.Label)
+		}
+
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("fields %q show %v, want %v", tt.fields, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package grafana
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRenderConcurrency = 4
+	defaultRenderBucket      = time.Minute
+	defaultImageRange        = 12 * time.Hour
+)
+
+// RenderPanels renders the images of the panels, in the same order, with the attributes over the time range.
+// A zero range means the last 12 hours like Panel.Image. The range is truncated to the render bucket, so
+// repeated calls within it request the same URLs: identical URLs are rendered once and served from the
+// image cache if one is set. Panels that fail to render get a nil image and are listed in a *PanelsError,
+// whose DashboardUID is set when all panels come from one dashboard.
+func (g *grafana) RenderPanels(ctx context.Context, panels []Panel, attrs ImageAttributes, from, to time.Time) ([][]byte, error) {
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	if from.IsZero() {
+		from = to.Add(-defaultImageRange)
+	}
+
+	if g.renderBucket > 0 {
+		from, to = from.Truncate(g.renderBucket), to.Truncate(g.renderBucket)
+	}
+
+	urls := make([]string, len(panels))
+	unique := make([]string, 0, len(panels))
+	seen := make(map[string]bool, len(panels))
+
+	for i, p := range panels {
+		if p.DashboardUID == "" {
+			continue
+		}
+
+		urls[i] = g.imageURL(ctx, p.DashboardUID, p.ID, attrs, from, to)
+
+		if !seen[urls[i]] {
+			seen[urls[i]] = true
+			unique = append(unique, urls[i])
+		}
+	}
+
+	images, errs := g.renderURLs(ctx, unique)
+
+	result := make([][]byte, len(panels))
+
+	var panelErrs []*PanelError
+
+	for i, p := range panels {
+		err := errors.New("panel has no dashboard uid")
+		if urls[i] != "" {
+			result[i], err = images[urls[i]], errs[urls[i]]
+		}
+
+		if err != nil {
+			panelErrs = append(panelErrs, &PanelError{PanelID: p.ID, Title: p.Title, Err: err})
+		}
+	}
+
+	if len(panelErrs) > 0 {
+		return result, &PanelsError{DashboardUID: dashboardOf(panels), Errors: panelErrs}
+	}
+
+	return result, nil
+}
+
+// dashboardOf returns the dashboard of the panels, empty when they come from several dashboards.
+func dashboardOf(panels []Panel) string {
+	var uid string
+
+	for _, p := range panels {
+		switch {
+		case p.DashboardUID == "":
+		case uid == "":
+			uid = p.DashboardUID
+		case uid != p.DashboardUID:
+			return ""
+		}
+	}
+
+	return uid
+}
+
+// renderURLs fetches the images from the image cache or renders them, at most renderConcurrency at a time.
+func (g *grafana) renderURLs(ctx context.Context, urls []string) (map[string][]byte, map[string]error) {
+	images := make(map[string][]byte, len(urls))
+	errs := make(map[string]error)
+
+	concurrency := g.renderConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultRenderConcurrency
+	}
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, concurrency)
+	)
+
+	for _, u := range urls {
+		if g.imageCache != nil {
+			if image, ok := g.imageCache.Get(u); ok {
+				images[u] = image
+
+				continue
+			}
+		}
+
+		wg.Add(1)
+
+		go func(u string) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				mu.Lock()
+				errs[u] = ctx.Err()
+				mu.Unlock()
+
+				return
+			}
+
+			image, err := g.panelPicture(ctx, u)
+			if err == nil && g.imageCache != nil {
+				if cacheErr := g.imageCache.Set(u, image); cacheErr != nil {
+					g.client.logger.Log(ctx, LogLevelWarn, "failed to cache panel image", "error", cacheErr)
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				errs[u] = err
+
+				return
+			}
+
+			images[u] = image
+		}(u)
+	}
+
+	wg.Wait()
+
+	return images, errs
+}
+
+// imageURL is the render URL of the panel with the attributes over the range, in the organization of ctx.
+func (g *grafana) imageURL(ctx context.Context, dashboardUID string, panelID int, attrs ImageAttributes, from, to time.Time) string {
+	u := fmt.Sprintf(
+		imageURLFormat,
+		g.client.url,
+		dashboardUID,
+		from.UnixMilli(),
+		to.UnixMilli(),
+		panelID,
+		attrs.Width,
+		attrs.Height,
+		attrs.Timezone,
+	)
+
+	if orgID := g.client.org(ctx); orgID != 0 {
+		u += fmt.Sprintf("&%s=%d", orgIDParam, orgID)
+	}
+
+	return u
+}
@@ -0,0 +1,122 @@
+package grafana
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const fakeRenderPath = "/render/d-solo/" + fakeDashboardUID + "/lido-monitors"
+
+func TestRenderPanels(t *testing.T) {
+	var (
+		requests, inFlight, maxInFlight int32
+		mu                              sync.Mutex
+		rendered                        = make(map[string]int)
+	)
+
+	png := readFixture(t, "panel.png")
+
+	f, inst := newFakeGrafanaClient(t, WithRenderConcurrency(2), WithImageCache(NewMemoryImageCache(time.Minute)))
+	f.handle(fakeRenderPath, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		rendered[r.URL.Query().Get("panelId")]++
+		mu.Unlock()
+
+		w.Header().Set(contentTypeHeader, "image/png")
+		w.Write(png)
+	})
+
+	panels := []Panel{
+		{ID: 2, DashboardUID: fakeDashboardUID},
+		{ID: 3, DashboardUID: fakeDashboardUID},
+		{ID: 4, DashboardUID: fakeDashboardUID},
+		{ID: 2, DashboardUID: fakeDashboardUID},
+		{ID: 5, DashboardUID: fakeDashboardUID},
+	}
+
+	from := time.Date(2022, 4, 15, 10, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+
+	images, err := inst.RenderPanels(context.Background(), panels, ImageAttributes{Width: 500, Height: 250}, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(images) != len(panels) {
+		t.Fatalf("got %d images, want %d", len(images), len(panels))
+	}
+
+	for i, image := range images {
+		if !bytes.Equal(image, png) {
+			t.Fatalf("wrong image %d", i)
+		}
+	}
+
+	if requests != 4 || rendered["2"] != 1 {
+		t.Fatalf("identical URLs are not deduplicated: %d requests, %v", requests, rendered)
+	}
+
+	if maxInFlight > 2 {
+		t.Fatalf("%d renders at a time, want at most 2", maxInFlight)
+	}
+
+	// the same bucket is served from the cache
+	if _, err = inst.RenderPanels(context.Background(), panels, ImageAttributes{Width: 500, Height: 250}, from, to.Add(30*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	if requests != 4 {
+		t.Fatalf("cached images are rendered again: %d requests", requests)
+	}
+}
+
+func TestRenderPanelsErrors(t *testing.T) {
+	_, inst := newFakeGrafanaClient(t)
+
+	panels := []Panel{
+		{ID: 2, DashboardUID: fakeDashboardUID},
+		{ID: 4, DashboardUID: "unknown"},
+		{ID: 4, Title: "no dashboard"},
+	}
+
+	images, err := inst.RenderPanels(context.Background(), panels, ImageAttributes{}, time.Time{}, time.Time{})
+	if !IsPartial(err) {
+		t.Fatalf("expected partial error, got %v", err)
+	}
+
+	if images[0] == nil || images[1] != nil || images[2] != nil {
+		t.Fatal("failed panels must have no image")
+	}
+
+	if errs := err.(*PanelsError).Errors; len(errs) != 2 || errs[0].PanelID != 4 || errs[1].Title != "no dashboard" {
+		t.Fatalf("wrong errors: %v", err)
+	}
+
+	if uid := err.(*PanelsError).DashboardUID; uid != "" {
+		t.Fatalf("panels of several dashboards must have no dashboard uid, got %q", uid)
+	}
+
+	_, err = inst.RenderPanels(context.Background(), panels[1:], ImageAttributes{}, time.Time{}, time.Time{})
+	if partial, ok := err.(*PanelsError); !ok || partial.DashboardUID != "unknown" {
+		t.Fatalf("expected errors of the unknown dashboard, got %v", err)
+	}
+}
@@ -0,0 +1,331 @@
+// Package report renders dashboard panels as Markdown, HTML or plain text.
+//
+// Every format is a Go template that can be overridden as a whole or in parts: the default
+// templates define "panel" and "value" sub-templates, so WithTemplate(`{{define "value"}}...{{end}}`)
+// changes how a single value is printed and keeps the rest of the layout.
+package report
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	texttemplate "text/template"
+
+	grafana "github.com/lidofinance/grafana-monitors-client"
+)
+
+// Format is an output format of a report.
+type Format int
+
+const (
+	Markdown Format = iota
+	HTML
+	Text
+)
+
+// GroupBy selects how panels are grouped in a report.
+type GroupBy int
+
+const (
+	// GroupNone keeps the dashboard order without group headers.
+	GroupNone GroupBy = iota
+	// GroupByRow groups panels under the dashboard rows they belong to.
+	GroupByRow
+	// GroupByAlertState groups panels by alert state, alerting first.
+	GroupByAlertState
+)
+
+// DefaultMaxValues is the number of values printed per panel unless WithMaxValues says otherwise.
+const DefaultMaxValues = 10
+
+const (
+	alertingState = "alerting"
+	pendingState  = "pending"
+	noDataState   = "no_data"
+	okState       = "ok"
+	pausedState   = "paused"
+
+	noAlertGroup = "No alert"
+)
+
+// alertStateOrder is the order of groups in GroupByAlertState reports.
+var alertStateOrder = []string{alertingState, pendingState, noDataState, okState, pausedState}
+
+var stateEmojis = map[string]string{
+	alertingState: "🔴",
+	pendingState:  "🟡",
+	noDataState:   "⚪",
+	okState:       "🟢",
+	pausedState:   "⏸",
+}
+
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+var templateFiles = map[Format]string{
+	Markdown: "templates/markdown.tmpl",
+	HTML:     "templates/html.tmpl",
+	Text:     "templates/text.tmpl",
+}
+
+// Data is passed to report templates.
+type Data struct {
+	Title    string
+	Groups   []Group
+	Alerting int
+}
+
+type Group struct {
+	Name   string
+	Panels []Panel
+}
+
+// Panel is a panel prepared for a template: values of all queries are flattened and truncated.
+type Panel struct {
+	grafana.Panel
+
+	Alerting bool
+	Emoji    string
+	Values   []grafana.LabelValue
+	// Hidden is the number of values left out by the truncation
+	Hidden int
+}
+
+type Option func(r *Renderer)
+
+// WithGroupBy sets panel grouping, GroupNone by default.
+func WithGroupBy(groupBy GroupBy) Option {
+	return func(r *Renderer) {
+		r.groupBy = groupBy
+	}
+}
+
+// WithMaxValues limits the number of values printed per panel. Zero or less prints all of them.
+func WithMaxValues(n int) Option {
+	return func(r *Renderer) {
+		r.maxValues = n
+	}
+}
+
+// WithTemplate parses text over the default template of the format. Top-level text replaces
+// the whole report, {{define}} blocks replace the sub-templates of the same name.
+func WithTemplate(text string) Option {
+	return func(r *Renderer) {
+		r.overrides = append(r.overrides, text)
+	}
+}
+
+// WithFuncs adds functions available to templates.
+func WithFuncs(funcs map[string]interface{}) Option {
+	return func(r *Renderer) {
+		for name, f := range funcs {
+			r.funcs[name] = f
+		}
+	}
+}
+
+type executor interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+// Renderer renders panels with the template of a single format. It is safe for concurrent use.
+type Renderer struct {
+	format    Format
+	groupBy   GroupBy
+	maxValues int
+	overrides []string
+	funcs     map[string]interface{}
+
+	tmpl executor
+}
+
+func New(format Format, opts ...Option) (*Renderer, error) {
+	file, ok := templateFiles[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown report format %d", format)
+	}
+
+	r := &Renderer{
+		format:    format,
+		maxValues: DefaultMaxValues,
+		funcs: map[string]interface{}{
+			"md": escapeMarkdown,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	text, err := templates.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	sources := append([]string{string(text)}, r.overrides...)
+
+	if format == HTML {
+		tmpl := htmltemplate.New("report").Funcs(r.funcs)
+
+		for _, source := range sources {
+			if tmpl, err = tmpl.Parse(source); err != nil {
+				return nil, fmt.Errorf("failed to parse report template: %w", err)
+			}
+		}
+
+		r.tmpl = tmpl
+
+		return r, nil
+	}
+
+	tmpl := texttemplate.New("report").Funcs(r.funcs)
+
+	for _, source := range sources {
+		if tmpl, err = tmpl.Parse(source); err != nil {
+			return nil, fmt.Errorf("failed to parse report template: %w", err)
+		}
+	}
+
+	r.tmpl = tmpl
+
+	return r, nil
+}
+
+// Render writes a report about the panels of a dashboard with the given title.
+func (r *Renderer) Render(w io.Writer, title string, panels []grafana.Panel) error {
+	if err := r.tmpl.Execute(w, r.Data(title, panels)); err != nil {
+		return fmt.Errorf("failed to render report: %w", err)
+	}
+
+	return nil
+}
+
+// RenderString returns the report as a string.
+func (r *Renderer) RenderString(title string, panels []grafana.Panel) (string, error) {
+	var b bytes.Buffer
+
+	if err := r.Render(&b, title, panels); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// Data prepares the template data: panels are truncated and grouped according to the options.
+func (r *Renderer) Data(title string, panels []grafana.Panel) Data {
+	data := Data{Title: title}
+
+	groups := make(map[string]*Group)
+
+	var order []string
+
+	for _, p := range panels {
+		panel := r.panel(p)
+
+		if panel.Alerting {
+			data.Alerting++
+		}
+
+		var name string
+
+		switch r.groupBy {
+		case GroupByRow:
+			name = p.Row
+		case GroupByAlertState:
+			name = strings.ToLower(p.Alert.State)
+			if name == "" {
+				name = noAlertGroup
+			}
+		}
+
+		g, ok := groups[name]
+		if !ok {
+			g = &Group{Name: name}
+			groups[name] = g
+			order = append(order, name)
+		}
+
+		g.Panels = append(g.Panels, panel)
+	}
+
+	if r.groupBy == GroupByAlertState {
+		order = alertStateGroupOrder(order)
+	}
+
+	for _, name := range order {
+		data.Groups = append(data.Groups, *groups[name])
+	}
+
+	return data
+}
+
+func (r *Renderer) panel(p grafana.Panel) Panel {
+	state := strings.ToLower(p.Alert.State)
+
+	panel := Panel{
+		Panel:    p,
+		Alerting: state == alertingState,
+		Emoji:    stateEmojis[state],
+	}
+
+	for _, cv := range p.CurrentValues {
+		for _, v := range cv.Values {
+			if r.maxValues > 0 && len(panel.Values) == r.maxValues {
+				panel.Hidden++
+
+				continue
+			}
+
+			panel.Values = append(panel.Values, v)
+		}
+	}
+
+	return panel
+}
+
+// alertStateGroupOrder sorts known states by severity and keeps unknown ones in dashboard order after them.
+func alertStateGroupOrder(names []string) []string {
+	present := make(map[string]bool, len(names))
+	for _, name := range names {
+		present[name] = true
+	}
+
+	ordered := make([]string, 0, len(names))
+
+	for _, state := range alertStateOrder {
+		if present[state] {
+			ordered = append(ordered, state)
+			delete(present, state)
+		}
+	}
+
+	for _, name := range names {
+		if present[name] && name != noAlertGroup {
+			ordered = append(ordered, name)
+		}
+	}
+
+	if present[noAlertGroup] {
+		ordered = append(ordered, noAlertGroup)
+	}
+
+	return ordered
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"_", `\_`,
+	"`", "\\`",
+	"[", `\[`,
+	"]", `\]`,
+	"#", `\#`,
+	"|", `\|`,
+)
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
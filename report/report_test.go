@@ -0,0 +1,142 @@
+package report
+
+import (
+	"strings"
+	"testing"
+
+	grafana "github.com/lidofinance/grafana-monitors-client"
+)
+
+func testPanels() []grafana.Panel {
+	return []grafana.Panel{
+		{
+			Title: "Update_global_index",
+			Row:   "Oracle",
+			Image: "http://grafana/render/d-solo/monitors/lido-monitors?panelId=2&width=1000",
+			CurrentValues: []grafana.CurrentValue{
+				{Values: []grafana.LabelValue{{Label: "gas used", Value: "512345"}}},
+				{Values: []grafana.LabelValue{{Label: "gas wanted", Value: "600000"}}},
+				{Values: []grafana.LabelValue{{Label: "uusd fee", Value: "90000", FormattedValue: "90.0 K"}}},
+			},
+		},
+		{
+			Title: "Slashing: Jailed Validators",
+			Row:   "Slashing",
+			Alert: grafana.Alert{Name: "Jailed validators alert", State: "alerting"},
+			CurrentValues: []grafana.CurrentValue{
+				{Values: []grafana.LabelValue{{Label: "<jailed>", Value: "2"}}},
+			},
+		},
+		{
+			Title: "Slashing: Missed Blocks",
+			Row:   "Slashing",
+			Alert: grafana.Alert{Name: "Missed blocks alert", State: "ok"},
+			Error: "query timed out",
+		},
+	}
+}
+
+func TestRenderFormats(t *testing.T) {
+	tests := map[Format][]string{
+		Markdown: {"# Lido monitors", "**1 alerting**", "### Update\\_global\\_index", "### 🔴 **Slashing: Jailed Validators**", "- gas used: `512345`", "[Image](http://grafana/render/", "Error: query timed out"},
+		HTML:     {"<h1>Lido monitors</h1>", `<div class="panel alerting"`, "<li>&lt;jailed&gt;: <code>2</code></li>", `panelId=2&amp;width=1000`, `<p class="error">Error: query timed out</p>`},
+		Text:     {"Lido monitors\n1 alerting", "[ALERTING] Slashing: Jailed Validators (alert: Jailed validators alert, alerting)", "  uusd fee: 90.0 K", "(alert: Missed blocks alert, ok)\n  error: query timed out"},
+	}
+
+	for format, contains := range tests {
+		r, err := New(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		report, err := r.RenderString("Lido monitors", testPanels())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, s := range contains {
+			if !strings.Contains(report, s) {
+				t.Fatalf("format %d: report has no %q:\n%s", format, s, report)
+			}
+		}
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	tests := map[GroupBy][]string{
+		GroupNone:         {""},
+		GroupByRow:        {"Oracle", "Slashing"},
+		GroupByAlertState: {"alerting", "ok", noAlertGroup},
+	}
+
+	for groupBy, groups := range tests {
+		r, err := New(Text, WithGroupBy(groupBy))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data := r.Data("", testPanels())
+		if len(data.Groups) != len(groups) {
+			t.Fatalf("group by %d: got %d groups, want %d", groupBy, len(data.Groups), len(groups))
+		}
+
+		for i, name := range groups {
+			if data.Groups[i].Name != name {
+				t.Fatalf("group by %d: got group %q at %d, want %q", groupBy, data.Groups[i].Name, i, name)
+			}
+		}
+	}
+}
+
+func TestMaxValues(t *testing.T) {
+	r, err := New(Markdown, WithMaxValues(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	panel := r.Data("", testPanels()).Groups[0].Panels[0]
+	if len(panel.Values) != 2 || panel.Hidden != 1 {
+		t.Fatalf("got %d values and %d hidden, want 2 and 1", len(panel.Values), panel.Hidden)
+	}
+
+	report, err := r.RenderString("", testPanels())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(report, "- … and 1 more") || strings.Contains(report, "uusd fee") {
+		t.Fatalf("values are not truncated:\n%s", report)
+	}
+}
+
+func TestTemplateOverride(t *testing.T) {
+	r, err := New(Text,
+		WithTemplate(`{{define "value"}}{{shout .Label}}={{.Value}}{{end}}`),
+		WithFuncs(map[string]interface{}{"shout": strings.ToUpper}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := r.RenderString("Lido monitors", testPanels())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(report, "  GAS USED=512345") || !strings.HasPrefix(report, "Lido monitors") {
+		t.Fatalf("value template is not overridden:\n%s", report)
+	}
+
+	r, err = New(HTML, WithTemplate(`{{len .Groups}} groups`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err = r.RenderString("", testPanels())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report != "1 groups" {
+		t.Fatalf("report template is not overridden: %q", report)
+	}
+}
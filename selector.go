@@ -0,0 +1,190 @@
+package grafana
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Selector picks panels of a dashboard. A panel is selected when it matches every field that is set,
+// so the zero Selector selects all panels.
+type Selector struct {
+	// IDs are panel ids, unique within a dashboard unlike titles
+	IDs []int
+	// Titles are exact panel titles
+	Titles []string
+	// TitleRegexp matches panel titles, e.g. ^Slashing:
+	TitleRegexp string
+	// TitleGlob matches panel titles with shell patterns, e.g. Slashing:*. Unlike path.Match,
+	// * and ? also match slashes, which are common in titles such as "Errors / 5xx"
+	TitleGlob string
+	// Rows are titles of the rows the panels are in
+	Rows []string
+	// Types are panel types such as stat, gauge or timeseries
+	Types []string
+	// HasAlert selects panels with an alert rule
+	HasAlert bool
+	// AlertStates are current alert states such as alerting, pending or ok
+	AlertStates []string
+}
+
+// panelMatcher is a Selector with its patterns compiled.
+type panelMatcher struct {
+	selector    Selector
+	titleRegexp *regexp.Regexp
+	titleGlob   *regexp.Regexp
+}
+
+func (s Selector) compile() (*panelMatcher, error) {
+	m := &panelMatcher{selector: s}
+
+	if s.TitleRegexp != "" {
+		re, err := regexp.Compile(s.TitleRegexp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile title regexp: %w", err)
+		}
+
+		m.titleRegexp = re
+	}
+
+	if s.TitleGlob != "" {
+		re, err := globRegexp(s.TitleGlob)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse title glob %q: %w", s.TitleGlob, err)
+		}
+
+		m.titleGlob = re
+	}
+
+	return m, nil
+}
+
+// Match reports whether the panel is selected. Panels are matched before their values are queried,
+// so only the panel fields and its alert are looked at.
+func (m *panelMatcher) Match(p Panel) bool {
+	s := m.selector
+
+	if len(s.IDs) > 0 && !containsInt(s.IDs, p.ID) {
+		return false
+	}
+
+	if len(s.Titles) > 0 && !containsString(s.Titles, p.Title) {
+		return false
+	}
+
+	if m.titleRegexp != nil && !m.titleRegexp.MatchString(p.Title) {
+		return false
+	}
+
+	if m.titleGlob != nil && !m.titleGlob.MatchString(p.Title) {
+		return false
+	}
+
+	if len(s.Rows) > 0 && !containsString(s.Rows, p.Row) {
+		return false
+	}
+
+	if len(s.Types) > 0 && !containsString(s.Types, p.Type) {
+		return false
+	}
+
+	if s.HasAlert && p.Alert.Name == "" {
+		return false
+	}
+
+	if len(s.AlertStates) > 0 && !containsFold(s.AlertStates, p.Alert.State) {
+		return false
+	}
+
+	return true
+}
+
+// MatchReference reports whether a library panel reference may be selected once its model is fetched.
+// The title, type and alert of a library panel come from its model, so only the id and row are looked at.
+func (m *panelMatcher) MatchReference(p panelData) bool {
+	s := m.selector
+
+	if len(s.IDs) > 0 && !containsInt(s.IDs, p.ID) {
+		return false
+	}
+
+	if len(s.Rows) > 0 && !containsString(s.Rows, p.Row) {
+		return false
+	}
+
+	return true
+}
+
+// globRegexp converts a shell pattern to a regexp matching whole strings: * matches any characters,
+// ? a single one, [...] and [!...] a character class, and \ escapes the next character.
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+
+	b.WriteString(`(?s)^`)
+
+	for i := 0; i < len(glob); i++ {
+		switch glob[i] {
+		case '*':
+			b.WriteString(`.*`)
+		case '?':
+			b.WriteString(`.`)
+		case '\\':
+			if i++; i == len(glob) {
+				return nil, errors.New("trailing backslash")
+			}
+
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return nil, errors.New("unterminated character class")
+			}
+
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+
+	b.WriteString(`$`)
+
+	return regexp.Compile(b.String())
+}
+
+func containsInt(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsString(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// containsFold compares case-insensitively, alert states are lower case in the API but not in the UI.
+func containsFold(values []string, v string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, v) {
+			return true
+		}
+	}
+
+	return false
+}
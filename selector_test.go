@@ -0,0 +1,97 @@
+package grafana
+
+import "testing"
+
+func TestSelectorMatch(t *testing.T) {
+	jailed := Panel{
+		ID:    4,
+		Title: "Slashing: Jailed Validators",
+		Type:  "graph",
+		Row:   "Slashing",
+		Alert: Alert{Name: "Jailed validators", State: "alerting"},
+	}
+
+	tests := map[string]struct {
+		selector Selector
+		match    bool
+	}{
+		"zero":               {Selector{}, true},
+		"id":                 {Selector{IDs: []int{2, 4}}, true},
+		"other id":           {Selector{IDs: []int{2}}, false},
+		"title":              {Selector{Titles: []string{"Slashing: Jailed Validators"}}, true},
+		"title regexp":       {Selector{TitleRegexp: "^Slashing:"}, true},
+		"other title regexp": {Selector{TitleRegexp: "^Oracle"}, false},
+		"title glob":         {Selector{TitleGlob: "Slashing:*"}, true},
+		"other title glob":   {Selector{TitleGlob: "*Oracle*"}, false},
+		"row":                {Selector{Rows: []string{"Slashing"}}, true},
+		"other row":          {Selector{Rows: []string{"Oracle"}}, false},
+		"type":               {Selector{Types: []string{"graph", "timeseries"}}, true},
+		"other type":         {Selector{Types: []string{"stat"}}, false},
+		"has alert":          {Selector{HasAlert: true}, true},
+		"alert state":        {Selector{AlertStates: []string{"Alerting"}}, true},
+		"other alert state":  {Selector{AlertStates: []string{"ok"}}, false},
+		"all fields":         {Selector{IDs: []int{4}, Rows: []string{"Slashing"}, HasAlert: true}, true},
+		"one field differs":  {Selector{IDs: []int{4}, Rows: []string{"Oracle"}}, false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			m, err := tt.selector.compile()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if m.Match(jailed) != tt.match {
+				t.Fatalf("expected match %v", tt.match)
+			}
+		})
+	}
+
+	m, err := Selector{HasAlert: true}.compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Match(Panel{ID: 2, Title: "Update global index"}) {
+		t.Fatal("panel without alert must not match")
+	}
+}
+
+func TestSelectorInvalid(t *testing.T) {
+	if _, err := (Selector{TitleRegexp: "("}).compile(); err == nil {
+		t.Fatal("expected error for invalid regexp")
+	}
+
+	if _, err := (Selector{TitleGlob: "["}).compile(); err == nil {
+		t.Fatal("expected error for invalid glob")
+	}
+}
+
+func TestTitleGlob(t *testing.T) {
+	tests := []struct {
+		glob  string
+		title string
+		match bool
+	}{
+		{"Errors*", "Errors / 5xx", true},
+		{"*/ 5xx", "Errors / 5xx", true},
+		{"Errors / ?xx", "Errors / 5xx", true},
+		{"Errors / [45]xx", "Errors / 5xx", true},
+		{"Errors / [!45]xx", "Errors / 5xx", false},
+		{"Errors", "Errors / 5xx", false},
+		{"Gas (wei)*", "Gas (wei) used", true},
+		{`Rate \*`, "Rate *", true},
+		{`Rate \*`, "Rate x", false},
+	}
+
+	for _, tt := range tests {
+		m, err := (Selector{TitleGlob: tt.glob}).compile()
+		if err != nil {
+			t.Fatalf("%s: %s", tt.glob, err)
+		}
+
+		if m.Match(Panel{Title: tt.title}) != tt.match {
+			t.Errorf("%s matching %q is %v", tt.glob, tt.title, !tt.match)
+		}
+	}
+}
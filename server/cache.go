@@ -0,0 +1,84 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// cache keeps values for a TTL and coalesces concurrent loads of the same key,
+// so a burst of requests for one dashboard results in a single Grafana call.
+type cache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]entry
+	calls   map[string]*call
+}
+
+type entry struct {
+	value   interface{}
+	expires time.Time
+}
+
+type call struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+func newCache(ttl time.Duration) *cache {
+	return &cache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]entry),
+		calls:   make(map[string]*call),
+	}
+}
+
+// get returns the cached value of the key or loads it. Errors are not cached.
+func (c *cache) get(key string, load func() (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+
+	if e, ok := c.entries[key]; ok && c.now().Before(e.expires) {
+		c.mu.Unlock()
+
+		return e.value, nil
+	}
+
+	if cl, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		<-cl.done
+
+		return cl.value, cl.err
+	}
+
+	cl := &call{done: make(chan struct{})}
+	c.calls[key] = cl
+	c.mu.Unlock()
+
+	cl.value, cl.err = load()
+
+	c.mu.Lock()
+	delete(c.calls, key)
+
+	if cl.err == nil && c.ttl > 0 {
+		c.evictExpired()
+		c.entries[key] = entry{value: cl.value, expires: c.now().Add(c.ttl)}
+	}
+
+	c.mu.Unlock()
+	close(cl.done)
+
+	return cl.value, cl.err
+}
+
+func (c *cache) evictExpired() {
+	now := c.now()
+
+	for key, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, key)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+// Package server exposes dashboard panels over HTTP as JSON.
+//
+// Endpoints:
+//
+//	GET /health
+//	GET /dashboards/{uid}/panels
+//	GET /dashboards/{uid}/panels/{title}
+//	GET /dashboards/{uid}/panels/{title}/image
+//
+// Titles are path-escaped, e.g. /dashboards/monitors/panels/Slashing:%20Jailed%20Validators.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	grafana "github.com/lidofinance/grafana-monitors-client"
+)
+
+// DefaultCacheTTL is how long panels and images are served from memory unless WithCacheTTL says otherwise.
+const DefaultCacheTTL = 30 * time.Second
+
+const (
+	dashboardsSegment = "dashboards"
+	panelsSegment     = "panels"
+	imageSegment      = "image"
+	healthPath        = "/health"
+)
+
+type Option func(h *Handler)
+
+// WithCacheTTL sets how long responses are cached. Zero disables caching; concurrent requests are still coalesced.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(h *Handler) {
+		h.panels.ttl = ttl
+		h.images.ttl = ttl
+	}
+}
+
+// Handler serves panels produced by Grafana.Panels.
+type Handler struct {
+	grafana grafana.Grafana
+
+	panels *cache
+	images *cache
+}
+
+func NewHandler(g grafana.Grafana, opts ...Option) *Handler {
+	h := &Handler{
+		grafana: g,
+		panels:  newCache(DefaultCacheTTL),
+		images:  newCache(DefaultCacheTTL),
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type healthResponse struct {
+	Status string `json:"status"`
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+
+		return
+	}
+
+	if r.URL.Path == healthPath {
+		writeJSON(w, http.StatusOK, healthResponse{Status: "ok"})
+
+		return
+	}
+
+	segments, err := pathSegments(r.URL)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	if len(segments) < 3 || segments[0] != dashboardsSegment || segments[2] != panelsSegment {
+		writeError(w, http.StatusNotFound, "not found")
+
+		return
+	}
+
+	uid := segments[1]
+
+	switch {
+	case len(segments) == 3:
+		h.servePanels(w, uid)
+	case len(segments) == 4:
+		h.servePanel(w, uid, segments[3])
+	case len(segments) == 5 && segments[4] == imageSegment:
+		h.serveImage(w, uid, segments[3])
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (h *Handler) servePanels(w http.ResponseWriter, uid string) {
+	panels, err := h.loadPanels(uid)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+
+		return
+	}
+
+	h.setCacheControl(w)
+	writeJSON(w, http.StatusOK, panels)
+}
+
+func (h *Handler) servePanel(w http.ResponseWriter, uid string, title string) {
+	panel, status, err := h.findPanel(uid, title)
+	if err != nil {
+		writeError(w, status, err.Error())
+
+		return
+	}
+
+	h.setCacheControl(w)
+	writeJSON(w, http.StatusOK, panel)
+}
+
+func (h *Handler) serveImage(w http.ResponseWriter, uid string, title string) {
+	panel, status, err := h.findPanel(uid, title)
+	if err != nil {
+		writeError(w, status, err.Error())
+
+		return
+	}
+
+	image, err := h.images.get(uid+"/"+title, func() (interface{}, error) {
+		return h.grafana.GetPanelPicture(panel.Image)
+	})
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+
+		return
+	}
+
+	body := image.([]byte)
+
+	h.setCacheControl(w)
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// loadPanels shares a single Panels call between concurrent requests. The call is detached from
+// the request context so that one client going away does not fail the others.
+func (h *Handler) loadPanels(uid string) ([]grafana.Panel, error) {
+	panels, err := h.panels.get(uid, func() (interface{}, error) {
+		return h.grafana.Panels(context.Background(), uid)
+	})
+	// partial results carry their errors in the panels and are served, but not cached
+	if err != nil && !grafana.IsPartial(err) {
+		return nil, err
+	}
+
+	return panels.([]grafana.Panel), nil
+}
+
+func (h *Handler) findPanel(uid string, title string) (grafana.Panel, int, error) {
+	panels, err := h.loadPanels(uid)
+	if err != nil {
+		return grafana.Panel{}, http.StatusBadGateway, err
+	}
+
+	for _, p := range panels {
+		if p.Title == title {
+			return p, http.StatusOK, nil
+		}
+	}
+
+	return grafana.Panel{}, http.StatusNotFound, fmt.Errorf("panel with name %s not found", title)
+}
+
+func (h *Handler) setCacheControl(w http.ResponseWriter) {
+	if h.panels.ttl > 0 {
+		w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", int(h.panels.ttl.Seconds())))
+	}
+}
+
+// pathSegments splits the escaped path so that titles may contain slashes.
+func pathSegments(u *url.URL) ([]string, error) {
+	parts := strings.Split(strings.Trim(u.EscapedPath(), "/"), "/")
+	segments := make([]string, 0, len(parts))
+
+	for _, p := range parts {
+		segment, err := url.PathUnescape(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path segment %s: %w", p, err)
+		}
+
+		segments = append(segments, segment)
+	}
+
+	return segments, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorResponse{Error: msg})
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	grafana "github.com/lidofinance/grafana-monitors-client"
+)
+
+var (
+	testImage  = []byte("\x89PNG\r\n\x1a\nfake")
+	testPanels = []grafana.Panel{
+		{ID: 2, Title: "Update global index", Image: "http://grafana/render/2"},
+		{ID: 4, Title: "Slashing: Jailed Validators", Image: "http://grafana/render/4", Alert: grafana.Alert{State: "alerting"}},
+		{ID: 6, Title: "in/out", Image: "http://grafana/render/6"},
+	}
+)
+
+func get(t *testing.T, h http.Handler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+	return w
+}
+
+func TestHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	g := grafana.NewMockGrafana(ctrl)
+	g.EXPECT().Panels(gomock.Any(), "monitors").Return(testPanels, nil).Times(1)
+	g.EXPECT().GetPanelPicture("http://grafana/render/4").Return(testImage, nil).Times(1)
+
+	h := NewHandler(g)
+
+	w := get(t, h, "/dashboards/monitors/panels")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+
+	var panels []grafana.Panel
+	if err := json.Unmarshal(w.Body.Bytes(), &panels); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(panels) != len(testPanels) {
+		t.Fatalf("got %d panels, want %d", len(panels), len(testPanels))
+	}
+
+	w = get(t, h, "/dashboards/monitors/panels/Slashing:%20Jailed%20Validators")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+
+	var panel grafana.Panel
+	if err := json.Unmarshal(w.Body.Bytes(), &panel); err != nil {
+		t.Fatal(err)
+	}
+
+	if panel.ID != 4 || panel.Alert.State != "alerting" {
+		t.Fatalf("unexpected panel %+v", panel)
+	}
+
+	if w = get(t, h, "/dashboards/monitors/panels/in%2Fout"); w.Code != http.StatusOK {
+		t.Fatalf("escaped slash in a title: got status %d", w.Code)
+	}
+
+	for i := 0; i < 2; i++ {
+		w = get(t, h, "/dashboards/monitors/panels/Slashing:%20Jailed%20Validators/image")
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" || !bytes.Equal(w.Body.Bytes(), testImage) {
+			t.Fatalf("unexpected image response %d %s", w.Code, w.Header())
+		}
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	g := grafana.NewMockGrafana(ctrl)
+	g.EXPECT().Panels(gomock.Any(), "monitors").Return(testPanels, nil).AnyTimes()
+	g.EXPECT().Panels(gomock.Any(), "broken").Return(nil, errors.New("status code is 500")).Times(2)
+
+	h := NewHandler(g)
+
+	tests := map[string]int{
+		"/dashboards/monitors/panels/Unknown":       http.StatusNotFound,
+		"/dashboards/monitors/panels/Unknown/image": http.StatusNotFound,
+		"/dashboards/monitors":                      http.StatusNotFound,
+		"/dashboards/monitors/panels/a/b":           http.StatusNotFound,
+		"/unknown":                                  http.StatusNotFound,
+		"/dashboards/broken/panels":                 http.StatusBadGateway,
+		// errors are not cached
+		"/dashboards/broken/panels/x": http.StatusBadGateway,
+		"/health":                     http.StatusOK,
+	}
+
+	for path, status := range tests {
+		if w := get(t, h, path); w.Code != status {
+			t.Fatalf("%s: got status %d, want %d", path, w.Code, status)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/dashboards/monitors/panels", nil))
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestHandlerPartialResults(t *testing.T) {
+	partial := []grafana.Panel{
+		testPanels[0],
+		{ID: 4, Title: "Slashing: Jailed Validators", Error: "status code is 500"},
+	}
+	partialErr := &grafana.PanelsError{DashboardUID: "monitors", Errors: []*grafana.PanelError{{PanelID: 4, Err: errors.New("status code is 500")}}}
+
+	ctrl := gomock.NewController(t)
+	g := grafana.NewMockGrafana(ctrl)
+	// partial results are not cached
+	g.EXPECT().Panels(gomock.Any(), "monitors").Return(partial, partialErr).Times(2)
+
+	h := NewHandler(g)
+
+	w := get(t, h, "/dashboards/monitors/panels")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+
+	w = get(t, h, "/dashboards/monitors/panels/Slashing:%20Jailed%20Validators")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+
+	var panel grafana.Panel
+	if err := json.Unmarshal(w.Body.Bytes(), &panel); err != nil {
+		t.Fatal(err)
+	}
+
+	if panel.Error != "status code is 500" {
+		t.Fatalf("panel error is not served: %+v", panel)
+	}
+}
+
+func TestHandlerCoalescesRequests(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	g := grafana.NewMockGrafana(ctrl)
+
+	release := make(chan struct{})
+
+	g.EXPECT().Panels(gomock.Any(), "monitors").DoAndReturn(func(ctx context.Context, uid string, filter ...string) ([]grafana.Panel, error) {
+		<-release
+
+		return testPanels, nil
+	}).Times(1)
+
+	// without caching only the concurrent requests share the call
+	h := NewHandler(g, WithCacheTTL(0))
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if w := get(t, h, "/dashboards/monitors/panels"); w.Code != http.StatusOK {
+				t.Errorf("got status %d", w.Code)
+			}
+		}()
+	}
+
+	// give the requests time to join the pending call
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+}
+
+func TestCacheExpires(t *testing.T) {
+	now := time.Now()
+
+	c := newCache(time.Minute)
+	c.now = func() time.Time { return now }
+
+	var loads int
+
+	load := func() (interface{}, error) {
+		loads++
+
+		return loads, nil
+	}
+
+	c.get("key", load)
+	c.get("key", load)
+
+	now = now.Add(time.Minute)
+
+	if v, _ := c.get("key", load); v != 2 || loads != 2 {
+		t.Fatalf("got value %v after %d loads, want a reload", v, loads)
+	}
+}
@@ -0,0 +1,294 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	snapshotsPath     = "/api/snapshots"
+	snapshotsListPath = "/api/dashboard/snapshots"
+	snapshotViewPath  = "/dashboard/snapshot/"
+)
+
+type snapshotRequestDTO struct {
+	Dashboard map[string]interface{} `json:"dashboard"`
+	Name      string                 `json:"name"`
+	// Expires is in seconds, zero never expires
+	Expires int64 `json:"expires,omitempty"`
+}
+
+type snapshotResponseDTO struct {
+	ID        int    `json:"id"`
+	Key       string `json:"key"`
+	DeleteKey string `json:"deleteKey"`
+	URL       string `json:"url"`
+	DeleteURL string `json:"deleteUrl"`
+}
+
+type snapshotListItemDTO struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Key         string    `json:"key"`
+	External    bool      `json:"external"`
+	ExternalURL string    `json:"externalUrl"`
+	Expires     time.Time `json:"expires"`
+	Created     time.Time `json:"created"`
+}
+
+// snapshotSeriesDTO is a series of panel snapshotData in the time series format Grafana still loads.
+type snapshotSeriesDTO struct {
+	Target     string           `json:"target"`
+	RefID      string           `json:"refId,omitempty"`
+	Datapoints [][2]interface{} `json:"datapoints"`
+}
+
+// CreateSnapshot freezes the dashboard with the data of its panels over the range, so it can be viewed
+// without Grafana credentials. A zero range is the dashboard time range, a zero expires never expires.
+func (g *grafana) CreateSnapshot(ctx context.Context, dashboardUID string, from, to time.Time, expires time.Duration) (*Snapshot, error) {
+	ctx = withAttributes(ctx, dashboardUIDKey.String(dashboardUID))
+
+	dashboard, raw, err := g.client.getDashboardResponse(ctx, dashboardUID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting dashboard response: %w", err)
+	}
+
+	now := time.Now()
+
+	if from.IsZero() || to.IsZero() {
+		if from, to, err = (timeRange{From: dashboard.Dashboard.Time.From, To: dashboard.Dashboard.Time.To}).Resolve(now); err != nil {
+			return nil, fmt.Errorf("failed to resolve dashboard time range: %w", err)
+		}
+	}
+
+	panels := dashboard.Data().Panels
+	if err = g.client.resolveLibraryPanels(ctx, panels); err != nil {
+		return nil, err
+	}
+
+	data := make(map[int][]snapshotSeriesDTO)
+
+	for _, p := range panels {
+		series, err := g.client.snapshotData(withAttributes(ctx, panelIDKey.Int(p.ID)), p, from, to, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get data of panel %s: %w", p.Title, err)
+		}
+
+		data[p.ID] = series
+	}
+
+	model, err := g.client.snapshotModel(ctx, raw, data)
+	if err != nil {
+		return nil, err
+	}
+
+	model["time"] = map[string]string{"from": from.UTC().Format(time.RFC3339), "to": to.UTC().Format(time.RFC3339)}
+	model["snapshot"] = map[string]string{"timestamp": now.UTC().Format(time.RFC3339)}
+
+	name, _ := model["title"].(string)
+
+	snapshot, err := g.client.createSnapshot(ctx, snapshotRequestDTO{
+		Dashboard: model,
+		Name:      name,
+		Expires:   int64(expires / time.Second),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	result := &Snapshot{
+		ID:        snapshot.ID,
+		Key:       snapshot.Key,
+		Name:      name,
+		URL:       snapshot.URL,
+		DeleteKey: snapshot.DeleteKey,
+		DeleteURL: snapshot.DeleteURL,
+		Created:   now,
+	}
+
+	if expires > 0 {
+		result.Expires = now.Add(expires)
+	}
+
+	return result, nil
+}
+
+// ListSnapshots returns the snapshots the credentials can see. Delete keys are only known on creation.
+func (g *grafana) ListSnapshots(ctx context.Context) ([]Snapshot, error) {
+	items, err := g.client.listSnapshots(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	snapshots := make([]Snapshot, 0, len(items))
+
+	for _, item := range items {
+		s := Snapshot{
+			ID:       item.ID,
+			Key:      item.Key,
+			Name:     item.Name,
+			URL:      g.client.url + snapshotViewPath + item.Key,
+			External: item.External,
+			Expires:  item.Expires,
+			Created:  item.Created,
+		}
+
+		if item.External && item.ExternalURL != "" {
+			s.URL = item.ExternalURL
+		}
+
+		snapshots = append(snapshots, s)
+	}
+
+	return snapshots, nil
+}
+
+// DeleteSnapshot deletes the snapshot with the key.
+func (g *grafana) DeleteSnapshot(ctx context.Context, key string) error {
+	if err := g.client.deleteSnapshot(ctx, key); err != nil {
+		return fmt.Errorf("failed to delete snapshot %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// snapshotData queries the panel targets over the range the panel displays, its relative time and
+// time shift applied, with the legend Grafana would show.
+func (c *client) snapshotData(ctx context.Context, p panelData, from, to, now time.Time) ([]snapshotSeriesDTO, error) {
+	r := p.TimeRange
+	r.From, r.To = strconv.FormatInt(from.UnixMilli(), 10), strconv.FormatInt(to.UnixMilli(), 10)
+
+	from, to, err := r.Resolve(now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve time range: %w", err)
+	}
+
+	result := make([]snapshotSeriesDTO, 0, len(p.Exprs))
+
+	for _, query := range p.Exprs {
+		series, err := c.exprQueryRange(ctx, query, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("error getting range values by query: %s; error: %w", query.Query, err)
+		}
+
+		labels := make([]LabelValue, len(series))
+		for i, s := range series {
+			labels[i].Label = s.Label
+		}
+
+		labels = applyLegendFormat(query, labels)
+
+		for i, s := range series {
+			item := snapshotSeriesDTO{
+				Target:     labels[i].Label,
+				RefID:      query.RefID,
+				Datapoints: make([][2]interface{}, 0, len(s.Points)),
+			}
+
+			for _, point := range s.Points {
+				var value interface{}
+				if v, err := strconv.ParseFloat(point.Value, 64); err == nil && !math.IsNaN(v) && !math.IsInf(v, 0) {
+					value = v
+				}
+
+				item.Datapoints = append(item.Datapoints, [2]interface{}{value, point.Time.UnixMilli()})
+			}
+
+			result = append(result, item)
+		}
+	}
+
+	return result, nil
+}
+
+// snapshotModel embeds the data of every panel in the dashboard model as snapshotData and replaces
+// library panels with their models, so the snapshot needs neither the datasources nor the library.
+func (c *client) snapshotModel(ctx context.Context, raw json.RawMessage, data map[int][]snapshotSeriesDTO) (map[string]interface{}, error) {
+	var model map[string]interface{}
+	if err := json.Unmarshal(raw, &model); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dashboard model: %w", err)
+	}
+
+	panels, _ := model["panels"].([]interface{})
+	if err := c.snapshotPanels(ctx, panels, data); err != nil {
+		return nil, err
+	}
+
+	return model, nil
+}
+
+func (c *client) snapshotPanels(ctx context.Context, panels []interface{}, data map[int][]snapshotSeriesDTO) error {
+	for i, item := range panels {
+		p, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if ref, ok := p["libraryPanel"].(map[string]interface{}); ok {
+			uid, _ := ref["uid"].(string)
+
+			model, err := c.libraryPanelModel(ctx, uid)
+			if err != nil {
+				return fmt.Errorf("failed to get library panel %s: %w", uid, err)
+			}
+
+			var resolved map[string]interface{}
+			if err = json.Unmarshal(model, &resolved); err != nil {
+				return fmt.Errorf("failed to unmarshal library panel %s model: %w", uid, err)
+			}
+
+			// the id and position belong to the dashboard, not to the library element
+			resolved["id"], resolved["gridPos"] = p["id"], p["gridPos"]
+			if _, ok := resolved["title"]; !ok {
+				resolved["title"] = p["title"]
+			}
+
+			p = resolved
+			panels[i] = p
+		}
+
+		if nested, ok := p["panels"].([]interface{}); ok {
+			if err := c.snapshotPanels(ctx, nested, data); err != nil {
+				return err
+			}
+		}
+
+		if id, ok := p["id"].(float64); ok {
+			if series, ok := data[int(id)]; ok {
+				p["snapshotData"] = series
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *client) createSnapshot(ctx context.Context, request snapshotRequestDTO) (*snapshotResponseDTO, error) {
+	var snapshot snapshotResponseDTO
+
+	if err := c.sendJSON(ctx, operationCreateSnapshot, http.MethodPost, snapshotsPath, request, &snapshot); err != nil {
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+func (c *client) listSnapshots(ctx context.Context) ([]snapshotListItemDTO, error) {
+	var snapshots []snapshotListItemDTO
+
+	if err := c.sendJSON(ctx, operationListSnapshots, http.MethodGet, snapshotsListPath, nil, &snapshots); err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+func (c *client) deleteSnapshot(ctx context.Context, key string) error {
+	return c.sendJSON(ctx, operationDeleteSnapshot, http.MethodDelete, fmt.Sprintf("%s/%s", snapshotsPath, url.PathEscape(key)), nil, nil)
+}
@@ -0,0 +1,196 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// snapshotRequest is the part of a snapshot request the tests look at.
+type snapshotRequest struct {
+	Name      string `json:"name"`
+	Expires   int64  `json:"expires"`
+	Dashboard struct {
+		Title  string            `json:"title"`
+		Time   map[string]string `json:"time"`
+		Panels []snapshotPanel   `json:"panels"`
+	} `json:"dashboard"`
+}
+
+type snapshotPanel struct {
+	ID           int                    `json:"id"`
+	Title        string                 `json:"title"`
+	LibraryPanel map[string]interface{} `json:"libraryPanel"`
+	Targets      []interface{}          `json:"targets"`
+	SnapshotData []struct {
+		Target     string          `json:"target"`
+		RefID      string          `json:"refId"`
+		Datapoints [][]interface{} `json:"datapoints"`
+	} `json:"snapshotData"`
+	Panels []snapshotPanel `json:"panels"`
+}
+
+func handleSnapshots(t *testing.T, f *fakeGrafana, request *snapshotRequest) {
+	f.handle(snapshotsPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get(contentTypeHeader) != jsonContentType {
+			t.Errorf("unexpected snapshot request %s %s", r.Method, r.Header.Get(contentTypeHeader))
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(body, request); err != nil {
+			t.Errorf("malformed snapshot request: %s", err)
+		}
+
+		w.Write([]byte(`{"id":7,"key":"abc","deleteKey":"del","url":"http://grafana/dashboard/snapshot/abc","deleteUrl":"http://grafana/api/snapshots-delete/del"}`))
+	})
+}
+
+func TestCreateSnapshot(t *testing.T) {
+	f, inst := newFakeGrafanaClient(t)
+
+	var request snapshotRequest
+
+	handleSnapshots(t, f, &request)
+
+	from := time.Date(2022, 4, 15, 9, 0, 0, 0, time.UTC)
+	to := from.Add(2 * time.Hour)
+
+	snapshot, err := inst.CreateSnapshot(context.Background(), fakeDashboardUID, from, to, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if snapshot.Key != "abc" || snapshot.DeleteURL != "http://grafana/api/snapshots-delete/del" || snapshot.URL == "" {
+		t.Fatalf("wrong snapshot: %+v", snapshot)
+	}
+
+	if snapshot.Name != "Lido monitors" || snapshot.Expires.IsZero() {
+		t.Fatalf("wrong snapshot name or expiry: %+v", snapshot)
+	}
+
+	if request.Name != "Lido monitors" || request.Expires != 3600 {
+		t.Fatalf("wrong snapshot request: %s, expires %d", request.Name, request.Expires)
+	}
+
+	if request.Dashboard.Time["from"] != "2022-04-15T09:00:00Z" || request.Dashboard.Time["to"] != "2022-04-15T11:00:00Z" {
+		t.Fatalf("wrong snapshot time: %v", request.Dashboard.Time)
+	}
+
+	panels := request.Dashboard.Panels
+	if len(panels) != 4 {
+		t.Fatalf("got %d panels, want 4", len(panels))
+	}
+
+	if data := panels[1].SnapshotData; len(data) != 3 || data[0].Target != "gas used" || data[0].RefID != "A" {
+		t.Fatalf("wrong data of panel 2: %+v", data)
+	}
+
+	// NaN is a gap
+	if data := panels[2].SnapshotData; len(data) != 2 || data[0].Datapoints[2][0] != nil || data[0].Datapoints[0][1] != float64(1650013200000) {
+		t.Fatalf("wrong data of panel 3: %+v", data)
+	}
+
+	if nested := panels[3].Panels; len(nested) != 1 || len(nested[0].SnapshotData) != 1 || nested[0].SnapshotData[0].Target != "jailed" {
+		t.Fatalf("panels of a collapsed row have no data: %+v", nested)
+	}
+}
+
+func TestCreateSnapshotLibraryPanel(t *testing.T) {
+	f, inst := newFakeGrafanaClient(t)
+
+	f.handle(dashboardPath+fakeDashboardUID, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"dashboard":{"id":1,"uid":"monitors","title":"Library","time":{"from":"now-1h","to":"now"},"panels":[
+			{"id":10,"gridPos":{"x":0,"y":0,"w":12,"h":8},"libraryPanel":{"uid":"jailed-lib","name":"Jailed validators"}}]}}`))
+	})
+	f.handle(libraryElementsPath+"jailed-lib", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(readFixture(t, "library_panel.json"))
+	})
+
+	var request snapshotRequest
+
+	handleSnapshots(t, f, &request)
+
+	if _, err := inst.CreateSnapshot(context.Background(), fakeDashboardUID, time.Time{}, time.Time{}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if request.Expires != 0 || request.Dashboard.Time["from"] == "" {
+		t.Fatalf("wrong snapshot request: %+v", request)
+	}
+
+	p := request.Dashboard.Panels[0]
+	if p.ID != 10 || p.LibraryPanel != nil || p.Title != "Slashing: Jailed Validators" || len(p.Targets) != 1 {
+		t.Fatalf("library panel is not inlined: %+v", p)
+	}
+
+	if len(p.SnapshotData) != 1 || len(p.SnapshotData[0].Datapoints) != 2 {
+		t.Fatalf("library panel has no data: %+v", p.SnapshotData)
+	}
+}
+
+func TestCreateSnapshotErrors(t *testing.T) {
+	f, inst := newFakeGrafanaClient(t)
+
+	f.handle(snapshotsPath, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	if _, err := inst.CreateSnapshot(context.Background(), fakeDashboardUID, time.Time{}, time.Time{}, 0); err == nil {
+		t.Fatal("expected error for forbidden snapshot")
+	}
+
+	f.handle(datasourcesPath+rangeQueryPath, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	if _, err := inst.CreateSnapshot(context.Background(), fakeDashboardUID, time.Time{}, time.Time{}, 0); err == nil {
+		t.Fatal("expected error for failed query")
+	}
+}
+
+func TestListAndDeleteSnapshots(t *testing.T) {
+	f, inst := newFakeGrafanaClient(t)
+
+	f.handle(snapshotsListPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"id":7,"name":"Lido monitors","key":"abc","external":false,"expires":"2022-04-16T10:00:00Z","created":"2022-04-15T10:00:00Z"},
+			{"id":8,"name":"Shared","key":"def","external":true,"externalUrl":"https://snapshots.raintank.io/dashboard/snapshot/def","expires":"2032-04-15T10:00:00Z","created":"2022-04-15T10:00:00Z"}]`))
+	})
+
+	var deleted string
+
+	f.handle(snapshotsPath+"/abc", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		deleted = "abc"
+		w.Write([]byte(`{"message":"Snapshot deleted","id":7}`))
+	})
+
+	snapshots, err := inst.ListSnapshots(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(snapshots) != 2 || snapshots[0].URL != f.URL+"/dashboard/snapshot/abc" || snapshots[0].Created.IsZero() {
+		t.Fatalf("wrong snapshots: %+v", snapshots)
+	}
+
+	if snapshots[1].URL != "https://snapshots.raintank.io/dashboard/snapshot/def" || !snapshots[1].External {
+		t.Fatalf("wrong external snapshot: %+v", snapshots[1])
+	}
+
+	if err = inst.DeleteSnapshot(context.Background(), "abc"); err != nil || deleted != "abc" {
+		t.Fatalf("snapshot is not deleted: %v", err)
+	}
+
+	if err = inst.DeleteSnapshot(context.Background(), "missing"); err == nil {
+		t.Fatal("expected error for missing snapshot")
+	}
+}
@@ -0,0 +1,187 @@
+package grafana
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/lidofinance/grafana-monitors-client"
+
+const (
+	operationGetDashboard    = "getDashboard"
+	operationAlertStates     = "alertStates"
+	operationDatasource      = "datasource"
+	operationDSQuery         = "dsQuery"
+	operationListDatasources = "listDatasources"
+	operationImage           = "image"
+	operationLibraryPanel    = "libraryPanel"
+	operationCreateSnapshot  = "createSnapshot"
+	operationListSnapshots   = "listSnapshots"
+	operationDeleteSnapshot  = "deleteSnapshot"
+
+	operationCreateAnnotation = "createAnnotation"
+	operationUpdateAnnotation = "updateAnnotation"
+	operationDeleteAnnotation = "deleteAnnotation"
+	operationAnnotations      = "annotations"
+
+	operationNotificationChannels = "notificationChannels"
+	operationContactPoints        = "contactPoints"
+	operationTestNotification     = "testNotification"
+	operationOrgs                 = "orgs"
+
+	requestDurationMetric = "grafana.client.request.duration"
+	requestErrorsMetric   = "grafana.client.request.errors"
+)
+
+var (
+	operationKey       = attribute.Key("grafana.operation")
+	dashboardUIDKey    = attribute.Key("grafana.dashboard.uid")
+	dashboardIDKey     = attribute.Key("grafana.dashboard.id")
+	panelIDKey         = attribute.Key("grafana.panel.id")
+	queryKey           = attribute.Key("grafana.query")
+	libraryPanelUIDKey = attribute.Key("grafana.library_panel.uid")
+	statusCodeKey      = attribute.Key("http.response.status_code")
+)
+
+type telemetryConfig struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	propagator     propagation.TextMapPropagator
+}
+
+// telemetry creates a span and records metrics for every call to Grafana or Prometheus.
+// Without a tracer and meter provider it is a no-op.
+type telemetry struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	duration   metric.Float64Histogram
+	errors     metric.Int64Counter
+}
+
+func newTelemetry(cfg telemetryConfig) *telemetry {
+	if cfg.tracerProvider == nil {
+		cfg.tracerProvider = trace.NewNoopTracerProvider()
+	}
+
+	if cfg.meterProvider == nil {
+		cfg.meterProvider = noop.NewMeterProvider()
+	}
+
+	if cfg.propagator == nil {
+		cfg.propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+	}
+
+	meter := cfg.meterProvider.Meter(instrumentationName)
+
+	// instrument errors only come from invalid names, which are constant here
+	duration, _ := meter.Float64Histogram(
+		requestDurationMetric,
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of requests to Grafana and Prometheus."))
+
+	failures, _ := meter.Int64Counter(
+		requestErrorsMetric,
+		metric.WithDescription("Failed requests to Grafana and Prometheus."))
+
+	return &telemetry{
+		tracer:     cfg.tracerProvider.Tracer(instrumentationName),
+		propagator: cfg.propagator,
+		duration:   duration,
+		errors:     failures,
+	}
+}
+
+type attributesKey struct{}
+
+type operationContextKey struct{}
+
+// withAttributes adds attributes to every span started with the returned context,
+// e.g. the dashboard uid and the panel id of the queries made for a panel.
+func withAttributes(ctx context.Context, attrs ...attribute.KeyValue) context.Context {
+	parent, _ := ctx.Value(attributesKey{}).([]attribute.KeyValue)
+
+	merged := make([]attribute.KeyValue, 0, len(parent)+len(attrs))
+	merged = append(merged, parent...)
+	merged = append(merged, attrs...)
+
+	return context.WithValue(ctx, attributesKey{}, merged)
+}
+
+// operation is an instrumented call, it ends with end.
+type operation struct {
+	telemetry *telemetry
+	ctx       context.Context
+	name      string
+	span      trace.Span
+	start     time.Time
+	status    int
+}
+
+func (t *telemetry) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, *operation) {
+	inherited, _ := ctx.Value(attributesKey{}).([]attribute.KeyValue)
+
+	ctx, span := t.tracer.Start(
+		ctx,
+		"grafana."+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(inherited...),
+		trace.WithAttributes(attrs...))
+
+	op := &operation{
+		telemetry: t,
+		ctx:       ctx,
+		name:      name,
+		span:      span,
+		start:     time.Now(),
+	}
+
+	return context.WithValue(ctx, operationContextKey{}, op), op
+}
+
+// end records the outcome of the call. err is the error returned by the call, not only the transport one.
+func (op *operation) end(err error) {
+	attrs := []attribute.KeyValue{operationKey.String(op.name)}
+
+	if op.status != 0 {
+		attrs = append(attrs, statusCodeKey.Int(op.status))
+		op.span.SetAttributes(statusCodeKey.Int(op.status))
+	}
+
+	if err != nil {
+		op.span.RecordError(err)
+		op.span.SetStatus(codes.Error, err.Error())
+		op.telemetry.errors.Add(op.ctx, 1, metric.WithAttributes(attrs...))
+	}
+
+	op.telemetry.duration.Record(op.ctx, time.Since(op.start).Seconds(), metric.WithAttributes(attrs...))
+	op.span.End()
+}
+
+// do sends the request with the trace context of its operation, logs it and remembers the response status.
+func (c *client) do(req *http.Request) (*http.Response, error) {
+	c.telemetry.propagator.Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+	c.setOrg(req)
+
+	start := time.Now()
+
+	resp, err := c.client.Do(req)
+	c.logResponse(req, resp, err, time.Since(start))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if op, ok := req.Context().Value(operationContextKey{}).(*operation); ok {
+		op.status = resp.StatusCode
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,145 @@
+package grafana
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+
+	return attribute.Value{}, false
+}
+
+func TestTelemetrySpans(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+
+	f, inst := newFakeGrafanaClient(t, WithTracerProvider(provider))
+
+	var traceparent string
+
+	f.handle(dashboardPath+fakeDashboardUID, func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.Write(readFixture(t, "dashboard.json"))
+	})
+
+	panels, err := inst.Panels(context.Background(), fakeDashboardUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = inst.GetPanelPicture(panels[0].Image); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := make(map[string]int)
+
+	for _, span := range spans.Ended() {
+		counts[span.Name()]++
+
+		if status, ok := spanAttribute(span, statusCodeKey); !ok || status.AsInt64() != http.StatusOK {
+			t.Errorf("span %s: unexpected status code %v", span.Name(), status.AsInterface())
+		}
+
+		if uid, ok := spanAttribute(span, dashboardUIDKey); span.Name() != "grafana."+operationAlertStates && (!ok || uid.AsString() != fakeDashboardUID) {
+			t.Errorf("span %s: unexpected dashboard uid %v", span.Name(), uid.AsInterface())
+		}
+
+		switch span.Name() {
+		case "grafana." + operationGetDashboard:
+			if want := "00-" + span.SpanContext().TraceID().String() + "-" + span.SpanContext().SpanID().String() + "-01"; traceparent != want {
+				t.Errorf("traceparent is %q, want %q", traceparent, want)
+			}
+		case "grafana." + operationDatasource:
+			if _, ok := spanAttribute(span, panelIDKey); !ok {
+				t.Errorf("datasource span has no panel id")
+			}
+
+			if query, ok := spanAttribute(span, queryKey); !ok || query.AsString() == "" {
+				t.Errorf("datasource span has no query")
+			}
+		case "grafana." + operationImage:
+			if id, ok := spanAttribute(span, panelIDKey); !ok || int(id.AsInt64()) != panels[0].ID {
+				t.Errorf("image span: unexpected panel id %v", id.AsInterface())
+			}
+		}
+	}
+
+	// one query per target of the three panels in the fixture
+	want := map[string]int{
+		"grafana." + operationGetDashboard: 1,
+		"grafana." + operationAlertStates:  1,
+		"grafana." + operationDatasource:   5,
+		"grafana." + operationImage:        1,
+	}
+
+	for name, n := range want {
+		if counts[name] != n {
+			t.Errorf("%d %s spans, want %d", counts[name], name, n)
+		}
+	}
+}
+
+func TestTelemetryErrors(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+
+	_, inst := newFakeGrafanaClient(t,
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+
+	if _, err := inst.Query(context.Background(), "unknown", time.Time{}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	ended := spans.Ended()
+	if len(ended) != 1 || ended[0].Status().Code != codes.Error {
+		t.Fatalf("unexpected spans %v", ended)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := make(map[string]metricdata.Aggregation)
+
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+
+	errors, ok := metrics[requestErrorsMetric].(metricdata.Sum[int64])
+	if !ok || len(errors.DataPoints) != 1 || errors.DataPoints[0].Value != 1 {
+		t.Fatalf("unexpected errors metric %+v", metrics[requestErrorsMetric])
+	}
+
+	attrs := errors.DataPoints[0].Attributes
+	if op, _ := attrs.Value(operationKey); op.AsString() != operationDatasource {
+		t.Errorf("unexpected operation %v", op.AsInterface())
+	}
+
+	if status, _ := attrs.Value(statusCodeKey); status.AsInt64() != http.StatusBadRequest {
+		t.Errorf("unexpected status code %v", status.AsInterface())
+	}
+
+	duration, ok := metrics[requestDurationMetric].(metricdata.Histogram[float64])
+	if !ok || len(duration.DataPoints) != 1 || duration.DataPoints[0].Count != 1 {
+		t.Fatalf("unexpected duration metric %+v", metrics[requestDurationMetric])
+	}
+}
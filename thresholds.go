@@ -0,0 +1,31 @@
+package grafana
+
+import "math"
+
+// Step returns the step a value falls into: the last one starting at or below the value, like
+// Grafana picks the color of a stat. Percentage steps are relative to min and max.
+func (t Thresholds) Step(value, min, max float64) *ThresholdStep {
+	if len(t.Steps) == 0 || math.IsNaN(value) {
+		return nil
+	}
+
+	if t.Mode == ThresholdsModePercentage {
+		if max == min {
+			value = 0
+		} else {
+			value = (value - min) / (max - min) * 100
+		}
+	}
+
+	active := t.Steps[0]
+
+	for _, s := range t.Steps[1:] {
+		if s.Value == nil || value < *s.Value {
+			break
+		}
+
+		active = s
+	}
+
+	return &active
+}
@@ -0,0 +1,78 @@
+package grafana
+
+import (
+	"testing"
+)
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func TestThresholdsStep(t *testing.T) {
+	absolute := Thresholds{
+		Mode: ThresholdsModeAbsolute,
+		Steps: []ThresholdStep{
+			{Color: "green"},
+			{Color: "orange", Value: float(10)},
+			{Color: "red", Value: float(20)},
+		},
+	}
+
+	percentage := Thresholds{
+		Mode: ThresholdsModePercentage,
+		Steps: []ThresholdStep{
+			{Color: "green"},
+			{Color: "red", Value: float(50)},
+		},
+	}
+
+	tests := []struct {
+		thresholds Thresholds
+		value      float64
+		min, max   float64
+		want       string
+	}{
+		{absolute, -5, 0, 0, "green"},
+		{absolute, 10, 0, 0, "orange"},
+		{absolute, 19.9, 0, 0, "orange"},
+		{absolute, 100, 0, 0, "red"},
+		{percentage, 40, 0, 100, "green"},
+		{percentage, 40, 0, 50, "red"},
+		{percentage, 5, 5, 5, "green"},
+	}
+
+	for _, tt := range tests {
+		step := tt.thresholds.Step(tt.value, tt.min, tt.max)
+		if step == nil || step.Color != tt.want {
+			t.Errorf("%s step of %v in [%v, %v] is %+v, want %s", tt.thresholds.Mode, tt.value, tt.min, tt.max, step, tt.want)
+		}
+	}
+
+	if step := (Thresholds{}).Step(1, 0, 0); step != nil {
+		t.Errorf("empty thresholds give step %+v", step)
+	}
+}
+
+func TestDisplayUsesValueRange(t *testing.T) {
+	p := panelData{
+		FieldConfig: fieldConfig{
+			Thresholds: Thresholds{
+				Mode:  ThresholdsModePercentage,
+				Steps: []ThresholdStep{{Color: "green"}, {Color: "red", Value: float(90)}},
+			},
+		},
+	}
+
+	values := []CurrentValue{{Values: []LabelValue{
+		{Label: "low", Value: "10"},
+		{Label: "high", Value: "20"},
+		{Label: "text", Value: "n/a"},
+	}}}
+
+	p.display(values)
+
+	got := values[0].Values
+	if got[0].Threshold.Color != "green" || got[1].Threshold.Color != "red" || got[2].Threshold != nil {
+		t.Fatalf("unexpected classification %+v", got)
+	}
+}
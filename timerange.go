@@ -0,0 +1,203 @@
+package grafana
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	nowKeyword = "now"
+
+	// defaultTimeFrom and defaultTimeTo are the range of a dashboard that has none saved
+	defaultTimeFrom = "now-6h"
+	defaultTimeTo   = nowKeyword
+)
+
+// timeRange is the range a panel displays: the dashboard time picker adjusted by the panel
+// relative time (timeFrom) and time shift (timeShift).
+type timeRange struct {
+	From string
+	To   string
+
+	PanelFrom  string
+	PanelShift string
+}
+
+// Resolve turns the range into absolute times relative to now.
+func (r timeRange) Resolve(now time.Time) (time.Time, time.Time, error) {
+	from, to := r.From, r.To
+	if from == "" {
+		from = defaultTimeFrom
+	}
+
+	if to == "" {
+		to = defaultTimeTo
+	}
+
+	// a panel relative time replaces the dashboard range with the last timeFrom
+	if r.PanelFrom != "" {
+		from, to = relativeTime(r.PanelFrom), nowKeyword
+	}
+
+	fromTime, err := parseGrafanaTime(from, now, false)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	toTime, err := parseGrafanaTime(to, now, true)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if r.PanelShift != "" {
+		shifted, err := parseGrafanaTime(relativeTime(r.PanelShift), now, false)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+
+		shift := now.Sub(shifted)
+		fromTime, toTime = fromTime.Add(-shift), toTime.Add(-shift)
+	}
+
+	return fromTime, toTime, nil
+}
+
+// relativeTime turns a panel time such as 1h into now-1h.
+func relativeTime(s string) string {
+	if strings.HasPrefix(s, nowKeyword) {
+		return s
+	}
+
+	return nowKeyword + "-" + s
+}
+
+// parseGrafanaTime parses a time picker value: now with offsets and rounding such as now-7d/d,
+// epoch milliseconds or an RFC 3339 time. Rounding goes to the end of the unit when roundUp is set,
+// as Grafana does for the end of a range.
+func parseGrafanaTime(s string, now time.Time, roundUp bool) (time.Time, error) {
+	s = strings.TrimSpace(s)
+
+	if !strings.HasPrefix(s, nowKeyword) {
+		if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.UnixMilli(ms), nil
+		}
+
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unsupported time %q", s)
+		}
+
+		return t, nil
+	}
+
+	t := now
+	rest := s[len(nowKeyword):]
+
+	for rest != "" {
+		op := rest[0]
+		rest = rest[1:]
+
+		switch op {
+		case '+', '-':
+			i := 0
+			for i < len(rest) && rest[i] >= '0' && rest[i] <= '9' {
+				i++
+			}
+
+			n := 1
+			if i > 0 {
+				n, _ = strconv.Atoi(rest[:i])
+			}
+
+			if i >= len(rest) {
+				return time.Time{}, fmt.Errorf("missing unit in time %q", s)
+			}
+
+			if op == '-' {
+				n = -n
+			}
+
+			var err error
+			if t, err = addTimeUnit(t, n, rest[i]); err != nil {
+				return time.Time{}, fmt.Errorf("invalid time %q: %w", s, err)
+			}
+
+			rest = rest[i+1:]
+		case '/':
+			if rest == "" {
+				return time.Time{}, fmt.Errorf("missing unit in time %q", s)
+			}
+
+			var err error
+			if t, err = roundTimeUnit(t, rest[0], roundUp); err != nil {
+				return time.Time{}, fmt.Errorf("invalid time %q: %w", s, err)
+			}
+
+			rest = rest[1:]
+		default:
+			return time.Time{}, fmt.Errorf("unsupported time %q", s)
+		}
+	}
+
+	return t, nil
+}
+
+func addTimeUnit(t time.Time, n int, unit byte) (time.Time, error) {
+	switch unit {
+	case 's':
+		return t.Add(time.Duration(n) * time.Second), nil
+	case 'm':
+		return t.Add(time.Duration(n) * time.Minute), nil
+	case 'h':
+		return t.Add(time.Duration(n) * time.Hour), nil
+	case 'd':
+		return t.AddDate(0, 0, n), nil
+	case 'w':
+		return t.AddDate(0, 0, 7*n), nil
+	case 'M':
+		return t.AddDate(0, n, 0), nil
+	case 'y':
+		return t.AddDate(n, 0, 0), nil
+	}
+
+	return time.Time{}, fmt.Errorf("unknown unit %q", unit)
+}
+
+func roundTimeUnit(t time.Time, unit byte, up bool) (time.Time, error) {
+	year, month, day := t.Date()
+
+	var start time.Time
+
+	switch unit {
+	case 's':
+		start = t.Truncate(time.Second)
+	case 'm':
+		start = time.Date(year, month, day, t.Hour(), t.Minute(), 0, 0, t.Location())
+	case 'h':
+		start = time.Date(year, month, day, t.Hour(), 0, 0, 0, t.Location())
+	case 'd':
+		start = time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+	case 'w':
+		// weeks start on Monday
+		start = time.Date(year, month, day-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location())
+	case 'M':
+		start = time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+	case 'y':
+		start = time.Date(year, time.January, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Time{}, fmt.Errorf("unknown unit %q", unit)
+	}
+
+	if !up {
+		return start, nil
+	}
+
+	end, err := addTimeUnit(start, 1, unit)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return end.Add(-time.Millisecond), nil
+}
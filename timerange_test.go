@@ -0,0 +1,69 @@
+package grafana
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseGrafanaTime(t *testing.T) {
+	now := time.Date(2022, 4, 15, 10, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		value   string
+		roundUp bool
+		want    time.Time
+	}{
+		{"now", false, now},
+		{"now-6h", false, now.Add(-6 * time.Hour)},
+		{"now-7d", false, now.AddDate(0, 0, -7)},
+		{"now+1M", false, now.AddDate(0, 1, 0)},
+		{"now/d", false, time.Date(2022, 4, 15, 0, 0, 0, 0, time.UTC)},
+		{"now/d", true, time.Date(2022, 4, 15, 23, 59, 59, int(999*time.Millisecond), time.UTC)},
+		{"now-1d/d", false, time.Date(2022, 4, 14, 0, 0, 0, 0, time.UTC)},
+		{"now/w", false, time.Date(2022, 4, 11, 0, 0, 0, 0, time.UTC)},
+		{"1650016800000", false, time.UnixMilli(1650016800000)},
+		{"2022-04-15T08:00:00Z", false, time.Date(2022, 4, 15, 8, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		got, err := parseGrafanaTime(tt.value, now, tt.roundUp)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.value, err)
+		}
+
+		if !got.Equal(tt.want) {
+			t.Errorf("%s is %s, want %s", tt.value, got, tt.want)
+		}
+	}
+
+	for _, value := range []string{"yesterday", "now-6", "now-1q", "now/"} {
+		if _, err := parseGrafanaTime(value, now, false); err == nil {
+			t.Errorf("%s: expected error", value)
+		}
+	}
+}
+
+func TestTimeRangeResolve(t *testing.T) {
+	now := time.Date(2022, 4, 15, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		r        timeRange
+		from, to time.Time
+	}{
+		{timeRange{}, now.Add(-6 * time.Hour), now},
+		{timeRange{From: "now-24h", To: "now"}, now.Add(-24 * time.Hour), now},
+		{timeRange{From: "now-24h", To: "now", PanelFrom: "1h"}, now.Add(-time.Hour), now},
+		{timeRange{From: "now-1h", To: "now", PanelShift: "1d"}, now.Add(-25 * time.Hour), now.Add(-24 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		from, to, err := tt.r.Resolve(now)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !from.Equal(tt.from) || !to.Equal(tt.to) {
+			t.Errorf("%+v resolves to %s - %s, want %s - %s", tt.r, from, to, tt.from, tt.to)
+		}
+	}
+}
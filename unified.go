@@ -0,0 +1,333 @@
+package grafana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	dsQueryPath         = "/api/ds/query"
+	datasourcesListPath = "/api/datasources"
+
+	contentTypeHeader = "Content-Type"
+	jsonContentType   = "application/json"
+
+	defaultMaxDataPoints = 1000
+
+	// datasourcesRetryInterval is how long a failure to list the datasources is returned before asking again
+	datasourcesRetryInterval = 5 * time.Minute
+)
+
+// datasourcesFailure is a failed datasource list request of an organization.
+type datasourcesFailure struct {
+	err   error
+	retry time.Time
+}
+
+func (c *client) unifiedCurrentValues(ctx context.Context, queries []expr, at time.Time) ([]CurrentValue, error) {
+	if len(queries) == 0 {
+		return []CurrentValue{}, nil
+	}
+
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	results, err := c.dsQuery(ctx, queries, at, at, true)
+	if err != nil {
+		return nil, fmt.Errorf("error getting current values by unified query: %w", err)
+	}
+
+	result := make([]CurrentValue, 0, len(queries))
+
+	for _, query := range queries {
+		r := results[query.RefID]
+		if r.Error != "" {
+			return nil, fmt.Errorf("error getting current values by query: %s; error: %s", query.Query, r.Error)
+		}
+
+		result = append(result, CurrentValue{
+			Query:  query.Query,
+			Values: applyLegendFormat(query, r.ToLabelValues()),
+		})
+	}
+
+	return result, nil
+}
+
+func (c *client) unifiedQuery(ctx context.Context, query string, at time.Time) ([]LabelValue, error) {
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	results, err := c.dsQuery(ctx, []expr{{RefID: "A", Query: query}}, at, at, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if r := results["A"]; r.Error != "" {
+		return nil, errors.New(r.Error)
+	}
+
+	return results["A"].ToLabelValues(), nil
+}
+
+func (c *client) unifiedQueryRange(ctx context.Context, query string, from, to time.Time, step time.Duration) ([]Series, error) {
+	return c.unifiedExprQueryRange(ctx, expr{RefID: "A", Query: query}, from, to, step)
+}
+
+// unifiedExprQueryRange runs the range query of a panel target on its datasource.
+func (c *client) unifiedExprQueryRange(ctx context.Context, query expr, from, to time.Time, step time.Duration) ([]Series, error) {
+	query.IntervalMs = step.Milliseconds()
+
+	results, err := c.dsQuery(ctx, []expr{query}, from, to, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if r := results[query.RefID]; r.Error != "" {
+		return nil, errors.New(r.Error)
+	}
+
+	return results[query.RefID].ToSeries(), nil
+}
+
+// dsQuery runs the queries in a single /api/ds/query request and returns the results by refId.
+func (c *client) dsQuery(ctx context.Context, queries []expr, from, to time.Time, instant bool) (_ map[string]dsQueryResultDTO, err error) {
+	exprs := make([]string, 0, len(queries))
+	for _, q := range queries {
+		exprs = append(exprs, q.Query)
+	}
+
+	ctx, op := c.telemetry.start(ctx, operationDSQuery, queryKey.StringSlice(exprs))
+	defer func() { op.end(err) }()
+
+	var response dsQueryResponseDTO
+
+	request := dsQueryRequestDTO{
+		Queries: make([]dsQueryDTO, 0, len(queries)),
+		From:    strconv.FormatInt(from.UnixMilli(), 10),
+		To:      strconv.FormatInt(to.UnixMilli(), 10),
+	}
+
+	for _, q := range queries {
+		ds, err := c.resolveDatasource(ctx, q.Datasource)
+		if err != nil {
+			return nil, err
+		}
+
+		query := dsQueryDTO{
+			RefID:         q.RefID,
+			Datasource:    ds,
+			Expr:          q.Query,
+			Instant:       instant,
+			Range:         !instant,
+			IntervalMs:    q.IntervalMs,
+			MaxDataPoints: q.MaxDataPoints,
+		}
+
+		if query.MaxDataPoints == 0 {
+			query.MaxDataPoints = defaultMaxDataPoints
+		}
+
+		if query.IntervalMs == 0 {
+			query.IntervalMs = defaultRangeStep.Milliseconds()
+		}
+
+		request.Queries = append(request.Queries, query)
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal ds query request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		fmt.Sprintf("%s%s", c.url, dsQueryPath),
+		bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create NewRequestWithContext: %w", err)
+	}
+
+	req.Header.Add(authHeader, c.token)
+	req.Header.Add(contentTypeHeader, jsonContentType)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to do request: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ds query response body: %w", err)
+	}
+
+	// query errors come back as 400 with per-refId errors in the body
+	if err = json.Unmarshal(body, &response); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed: status code is %d", resp.StatusCode)
+		}
+
+		return nil, fmt.Errorf("failed to unmarshal ds query response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK && len(response.Results) == 0 {
+		if response.Message != "" {
+			return nil, fmt.Errorf("failed: status code is %d: %s", resp.StatusCode, response.Message)
+		}
+
+		return nil, fmt.Errorf("failed: status code is %d", resp.StatusCode)
+	}
+
+	return response.Results, nil
+}
+
+// resolveDatasource turns a dashboard datasource reference into one with a uid.
+func (c *client) resolveDatasource(ctx context.Context, ref datasourceRef) (datasourceRef, error) {
+	if ref.UID != "" && !strings.HasPrefix(ref.UID, "$") {
+		return ref, nil
+	}
+
+	info, err := c.lookupDatasource(ctx, ref)
+	if err != nil {
+		return datasourceRef{}, err
+	}
+
+	return datasourceRef{UID: info.UID, Type: info.Type}, nil
+}
+
+// lookupDatasource finds the datasource a dashboard reference points to. Empty references and
+// template variables such as ${DS_PROMETHEUS} resolve to the default datasource.
+func (c *client) lookupDatasource(ctx context.Context, ref datasourceRef) (datasourceInfoDTO, error) {
+	datasources, err := c.listDatasources(ctx)
+	if err != nil {
+		return datasourceInfoDTO{}, err
+	}
+
+	uid := ref.UID
+	if strings.HasPrefix(uid, "$") {
+		uid = ""
+	}
+
+	name := ref.Name
+	if strings.HasPrefix(name, "$") {
+		name = ""
+	}
+
+	for _, ds := range datasources {
+		switch {
+		case uid != "":
+			if ds.UID == uid {
+				return ds, nil
+			}
+		case name != "":
+			if ds.Name == name {
+				return ds, nil
+			}
+		case ds.IsDefault:
+			return ds, nil
+		}
+	}
+
+	switch {
+	case uid != "":
+		return datasourceInfoDTO{}, fmt.Errorf("datasource %s not found", uid)
+	case name != "":
+		return datasourceInfoDTO{}, fmt.Errorf("datasource %s not found", name)
+	}
+
+	return datasourceInfoDTO{}, errors.New("default datasource not found")
+}
+
+// listDatasources returns the datasources visible to the token. The list is fetched once per client and organization.
+// A failure, e.g. of a token without the permission to read datasources, is logged once and returned
+// without asking Grafana again for datasourcesRetryInterval.
+func (c *client) listDatasources(ctx context.Context) ([]datasourceInfoDTO, error) {
+	c.datasourcesMu.Lock()
+	defer c.datasourcesMu.Unlock()
+
+	orgID := c.org(ctx)
+
+	if datasources, ok := c.datasources[orgID]; ok {
+		return datasources, nil
+	}
+
+	if failure, ok := c.datasourcesErr[orgID]; ok && time.Now().Before(failure.retry) {
+		return nil, failure.err
+	}
+
+	datasources, err := c.getDatasources(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			c.logger.Log(ctx, LogLevelWarn, "failed to list datasources", "error", err, "retry_in", datasourcesRetryInterval)
+
+			if c.datasourcesErr == nil {
+				c.datasourcesErr = make(map[int64]datasourcesFailure)
+			}
+
+			c.datasourcesErr[orgID] = datasourcesFailure{err: err, retry: time.Now().Add(datasourcesRetryInterval)}
+		}
+
+		return nil, err
+	}
+
+	if c.datasources == nil {
+		c.datasources = make(map[int64][]datasourceInfoDTO)
+	}
+
+	c.datasources[orgID] = datasources
+
+	return datasources, nil
+}
+
+func (c *client) getDatasources(ctx context.Context) (_ []datasourceInfoDTO, err error) {
+	ctx, op := c.telemetry.start(ctx, operationListDatasources)
+	defer func() { op.end(err) }()
+
+	var datasources []datasourceInfoDTO
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		fmt.Sprintf("%s%s", c.url, datasourcesListPath),
+		nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create NewRequestWithContext: %w", err)
+	}
+
+	req.Header.Add(authHeader, c.token)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to do request: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed: status code is %d", resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read datasources response body: %w", err)
+	}
+
+	if err = json.Unmarshal(body, &datasources); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal datasources response: %w", err)
+	}
+
+	return datasources, nil
+}
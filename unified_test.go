@@ -0,0 +1,73 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestUnifiedFallbackWhenProxyForbidden(t *testing.T) {
+	f, inst := newFakeGrafanaClient(t)
+
+	var proxied, unified int
+
+	f.handle(datasourcesPath+instantQueryPath, func(w http.ResponseWriter, r *http.Request) {
+		proxied++
+		w.WriteHeader(http.StatusForbidden)
+	})
+	f.handle(datasourcesListPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id":1,"uid":"prom","name":"Prometheus","type":"prometheus","isDefault":true}]`))
+	})
+	f.handle(dsQueryPath, func(w http.ResponseWriter, r *http.Request) {
+		unified++
+
+		var request dsQueryRequestDTO
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("failed to decode ds query request: %s", err)
+		}
+
+		results := make(map[string]interface{})
+
+		for _, q := range request.Queries {
+			// the stat panel is reduced over a range, the others are instant
+			if q.Datasource.UID != "prom" || q.Instant != (q.Expr != "config_crc32") {
+				t.Errorf("unexpected query %+v", q)
+			}
+
+			results[q.RefID] = map[string]interface{}{
+				"frames": []interface{}{map[string]interface{}{
+					"schema": map[string]interface{}{"fields": []interface{}{
+						map[string]interface{}{"name": "Time", "type": "time"},
+						map[string]interface{}{"name": "Value", "type": "number", "labels": map[string]string{"label": q.RefID}},
+					}},
+					"data": map[string]interface{}{"values": []interface{}{[]int64{1650016800000}, []float64{1}}},
+				}},
+			}
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	})
+
+	for i := 0; i < 2; i++ {
+		panels, err := inst.Panels(context.Background(), fakeDashboardUID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, p := range panels {
+			if len(p.CurrentValues) == 0 || len(p.CurrentValues[0].Values) == 0 {
+				t.Fatalf("current values is empty! title is %s", p.Title)
+			}
+		}
+	}
+
+	if proxied != 1 {
+		t.Fatalf("got %d proxied requests, want 1", proxied)
+	}
+
+	// one batched request per panel for both rounds
+	if unified != 6 {
+		t.Fatalf("got %d unified requests, want 6", unified)
+	}
+}
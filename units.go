@@ -0,0 +1,218 @@
+package grafana
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Units of fieldConfig.defaults.unit understood by FormatValue, named as in Grafana.
+const (
+	UnitNone        = "none"
+	UnitShort       = "short"
+	UnitPercent     = "percent"
+	UnitPercentUnit = "percentunit"
+
+	UnitBytes      = "bytes"
+	UnitKibibytes  = "kbytes"
+	UnitMebibytes  = "mbytes"
+	UnitGibibytes  = "gbytes"
+	UnitTebibytes  = "tbytes"
+	UnitDecBytes   = "decbytes"
+	UnitKilobytes  = "deckbytes"
+	UnitMegabytes  = "decmbytes"
+	UnitGigabytes  = "decgbytes"
+	UnitTerabytes  = "dectbytes"
+	UnitBits       = "bits"
+	UnitDecBits    = "decbits"
+	UnitBytesPerS  = "Bps"
+	UnitBitsPerS   = "bps"
+	UnitNanosecs   = "ns"
+	UnitMicrosecs  = "µs"
+	UnitMillisecs  = "ms"
+	UnitSeconds    = "s"
+	UnitMinutes    = "m"
+	UnitHours      = "h"
+	UnitDays       = "d"
+	UnitOpsPerSec  = "ops"
+	UnitReqsPerSec = "reqps"
+	UnitOpsPerMin  = "opm"
+
+	UnitCurrencyUSD = "currencyUSD"
+	UnitCurrencyEUR = "currencyEUR"
+	UnitCurrencyGBP = "currencyGBP"
+	UnitCurrencyJPY = "currencyJPY"
+	UnitCurrencyRUB = "currencyRUB"
+)
+
+const (
+	prefixUnit = "prefix:"
+	suffixUnit = "suffix:"
+)
+
+var (
+	shortSuffixes    = []string{"", " K", " Mil", " Bil", " Tri", " Quadr", " Quint", " Sext", " Sept"}
+	iecByteSuffixes  = []string{" B", " KiB", " MiB", " GiB", " TiB", " PiB", " EiB", " ZiB", " YiB"}
+	siByteSuffixes   = []string{" B", " kB", " MB", " GB", " TB", " PB", " EB", " ZB", " YB"}
+	iecBitSuffixes   = []string{" b", " Kib", " Mib", " Gib", " Tib", " Pib", " Eib", " Zib", " Yib"}
+	siBitSuffixes    = []string{" b", " kb", " Mb", " Gb", " Tb", " Pb", " Eb", " Zb", " Yb"}
+	countSuffixes    = []string{"", "K", "M", "B", "T"}
+	currencySymbols  = map[string]string{UnitCurrencyUSD: "$", UnitCurrencyEUR: "€", UnitCurrencyGBP: "£", UnitCurrencyJPY: "¥", UnitCurrencyRUB: "₽"}
+	rateUnitSuffixes = map[string]string{UnitOpsPerSec: " ops/s", UnitReqsPerSec: " req/s", "rps": " rd/s", "wps": " wr/s", "iops": " io/s", UnitOpsPerMin: " ops/min"}
+)
+
+// FormatValue formats a value the way Grafana displays it in the unit. Without decimals they are
+// picked from the magnitude of the value. Unknown units are appended to the value, as Grafana does
+// for custom units.
+func FormatValue(value float64, unit string, decimals *int) string {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	switch unit {
+	case "", UnitNone:
+		return toFixed(value, decimals)
+	case UnitShort:
+		return scaled(value, decimals, 1000, shortSuffixes, 0)
+	case UnitPercent:
+		return toFixed(value, decimals) + "%"
+	case UnitPercentUnit:
+		return toFixed(value*100, decimals) + "%"
+	case UnitBytes:
+		return scaled(value, decimals, 1024, iecByteSuffixes, 0)
+	case UnitKibibytes, UnitMebibytes, UnitGibibytes, UnitTebibytes:
+		return scaled(value, decimals, 1024, iecByteSuffixes, byteUnitOffset(unit))
+	case UnitDecBytes:
+		return scaled(value, decimals, 1000, siByteSuffixes, 0)
+	case UnitKilobytes, UnitMegabytes, UnitGigabytes, UnitTerabytes:
+		return scaled(value, decimals, 1000, siByteSuffixes, byteUnitOffset(unit))
+	case UnitBits:
+		return scaled(value, decimals, 1024, iecBitSuffixes, 0)
+	case UnitDecBits:
+		return scaled(value, decimals, 1000, siBitSuffixes, 0)
+	case UnitBytesPerS:
+		return scaled(value, decimals, 1000, siByteSuffixes, 0) + "/s"
+	case UnitBitsPerS:
+		return scaled(value, decimals, 1000, siBitSuffixes, 0) + "ps"
+	case UnitNanosecs:
+		return formatDuration(value/1e9, decimals)
+	case UnitMicrosecs:
+		return formatDuration(value/1e6, decimals)
+	case UnitMillisecs:
+		return formatDuration(value/1e3, decimals)
+	case UnitSeconds:
+		return formatDuration(value, decimals)
+	case UnitMinutes:
+		return formatDuration(value*60, decimals)
+	case UnitHours:
+		return formatDuration(value*3600, decimals)
+	case UnitDays:
+		return formatDuration(value*86400, decimals)
+	}
+
+	if symbol, ok := currencySymbols[unit]; ok {
+		formatted := scaled(math.Abs(value), decimals, 1000, countSuffixes, 0)
+		if value < 0 {
+			return "-" + symbol + formatted
+		}
+
+		return symbol + formatted
+	}
+
+	if suffix, ok := rateUnitSuffixes[unit]; ok {
+		return scaled(value, decimals, 1000, countSuffixes, 0) + suffix
+	}
+
+	switch {
+	case strings.HasPrefix(unit, prefixUnit):
+		return strings.TrimPrefix(unit, prefixUnit) + toFixed(value, decimals)
+	case strings.HasPrefix(unit, suffixUnit):
+		return toFixed(value, decimals) + strings.TrimPrefix(unit, suffixUnit)
+	}
+
+	return toFixed(value, decimals) + " " + unit
+}
+
+func byteUnitOffset(unit string) int {
+	switch unit {
+	case UnitKibibytes, UnitKilobytes:
+		return 1
+	case UnitMebibytes, UnitMegabytes:
+		return 2
+	case UnitGibibytes, UnitGigabytes:
+		return 3
+	}
+
+	return 4
+}
+
+// scaled divides the value by the factor until it is below it and appends the suffix of the step.
+// offset is the step of the unit itself, e.g. 1 for kilobytes.
+func scaled(value float64, decimals *int, factor float64, suffixes []string, offset int) string {
+	step := offset
+
+	for math.Abs(value) >= factor && step < len(suffixes)-1 {
+		value /= factor
+		step++
+	}
+
+	return toFixed(value, decimals) + suffixes[step]
+}
+
+// formatDuration picks the largest time unit below the value, given in seconds.
+func formatDuration(seconds float64, decimals *int) string {
+	abs := math.Abs(seconds)
+
+	switch {
+	case abs == 0:
+		return toFixed(0, decimals) + " s"
+	case abs < 1e-6:
+		return toFixed(seconds*1e9, decimals) + " ns"
+	case abs < 1e-3:
+		return toFixed(seconds*1e6, decimals) + " µs"
+	case abs < 1:
+		return toFixed(seconds*1e3, decimals) + " ms"
+	case abs < 60:
+		return toFixed(seconds, decimals) + " s"
+	case abs < 3600:
+		return toFixed(seconds/60, decimals) + " min"
+	case abs < 86400:
+		return toFixed(seconds/3600, decimals) + " hour"
+	case abs < 604800:
+		return toFixed(seconds/86400, decimals) + " day"
+	case abs < 31536000:
+		return toFixed(seconds/604800, decimals) + " week"
+	}
+
+	return toFixed(seconds/31536000, decimals) + " year"
+}
+
+func toFixed(value float64, decimals *int) string {
+	d := autoDecimals(value)
+	if decimals != nil {
+		d = *decimals
+	}
+
+	return strconv.FormatFloat(value, 'f', d, 64)
+}
+
+// autoDecimals keeps two or three significant digits of fractions and none of whole numbers,
+// like Grafana's getDecimalsForValue.
+func autoDecimals(value float64) int {
+	if value == math.Trunc(value) {
+		return 0
+	}
+
+	magnitude := math.Floor(math.Log10(math.Abs(value)))
+	dec := int(-magnitude) + 1
+
+	if norm := value / math.Pow(10, -float64(dec)); math.Abs(norm) > 2.25 {
+		dec++
+	}
+
+	if dec < 0 {
+		return 0
+	}
+
+	return dec
+}
@@ -0,0 +1,50 @@
+package grafana
+
+import (
+	"math"
+	"testing"
+)
+
+func places(d int) *int {
+	return &d
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		value    float64
+		unit     string
+		decimals *int
+		want     string
+	}{
+		{1.2345678e+09, UnitDecBytes, nil, "1.23 GB"},
+		{1.2345678e+09, UnitBytes, nil, "1.15 GiB"},
+		{1536, UnitKibibytes, nil, "1.50 MiB"},
+		{512, UnitBytes, nil, "512 B"},
+		{2500, UnitDecBits, nil, "2.50 kb"},
+		{45.2, UnitPercent, nil, "45.2%"},
+		{0.452, UnitPercentUnit, nil, "45.2%"},
+		{12600, UnitSeconds, nil, "3.50 hour"},
+		{1500, UnitMillisecs, nil, "1.50 s"},
+		{0.25, UnitSeconds, nil, "250 ms"},
+		{90, UnitMinutes, places(1), "1.5 hour"},
+		{3, UnitDays, nil, "3 day"},
+		{1234.5, UnitCurrencyUSD, nil, "$1.23K"},
+		{-20, UnitCurrencyEUR, nil, "-€20"},
+		{2500, UnitOpsPerSec, nil, "2.50K ops/s"},
+		{12, UnitReqsPerSec, nil, "12 req/s"},
+		{512345, UnitShort, places(1), "512.3 K"},
+		{3735928559, UnitShort, nil, "3.74 Bil"},
+		{3735928559, "", nil, "3735928559"},
+		{0.5, UnitNone, nil, "0.500"},
+		{7, "validators", nil, "7 validators"},
+		{7, "suffix:x", nil, "7x"},
+		{7, "prefix:#", nil, "#7"},
+		{math.NaN(), UnitPercent, nil, "NaN"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatValue(tt.value, tt.unit, tt.decimals); got != tt.want {
+			t.Errorf("%v in %q is %q, want %q", tt.value, tt.unit, got, tt.want)
+		}
+	}
+}